@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,6 +22,8 @@ import (
 	lksdk "github.com/livekit/server-sdk-go/v2"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
+
+	"github.com/RMS-Server/rms-chatroom/backend/music-service/sourceresolver"
 )
 
 // Constants matching Ingress exactly
@@ -28,6 +33,88 @@ const (
 	OpusBitrate   = 256000
 	OpusFrameSize = 20
 	DefaultVolume = 0.5 // Default volume (0.0-1.0)
+
+	// GaplessPreloadMs is how far from the end of the current track (by
+	// positionMs/durationMs) we start prerolling the next queued song.
+	GaplessPreloadMs = 10000
+
+	// TargetLUFS is the loudness target for auto-measured normalization.
+	// GStreamer's ReplayGain reference level (89dB SPL) already lands
+	// close to this, so auto-measure mode reuses rganalysis/rgvolume as-is.
+	TargetLUFS = -14.0
+
+	// DefaultFadeMs is how long Play/Resume fade in and Pause/Stop/Seek
+	// fade out, to avoid audible clicks at the volume element.
+	DefaultFadeMs = 150
+
+	// DefaultCrossfadeMs is how long the outgoing/incoming bins overlap
+	// during a gapless queue transition when CrossfadeMs isn't configured.
+	DefaultCrossfadeMs = 3000
+
+	// volumeFadeStepInterval is the tick rate used to animate the `volume`
+	// element's property for fades. Crossfades don't use it; armCrossfadeRamp
+	// schedules them on the pipeline clock instead.
+	volumeFadeStepInterval = 20 * time.Millisecond
+
+	// liveReconnectBaseDelay/liveReconnectMaxDelay bound the exponential
+	// backoff used to reconnect a live source after it drops.
+	liveReconnectBaseDelay = 1 * time.Second
+	liveReconnectMaxDelay  = 30 * time.Second
+)
+
+// LiveKit data channel topics used to keep room clients in sync with
+// playback without polling /progress.
+const (
+	// DataTopicProgress carries periodic position/metadata updates.
+	DataTopicProgress = "music-progress"
+	// DataTopicEvents carries one-shot state-transition notices.
+	DataTopicEvents = "music-events"
+	// DataTopicControl carries inbound DJ playback commands.
+	DataTopicControl = "music-control"
+
+	// progressPublishInterval is how often DataTopicProgress updates are
+	// sent (~4Hz).
+	progressPublishInterval = 250 * time.Millisecond
+)
+
+// SongKind distinguishes a regular on-demand file/URL track from a
+// continuously-streamed live source (Icecast/Shoutcast ICY or HLS), which
+// has no fixed duration and can't be seeked.
+type SongKind string
+
+const (
+	SongKindFile SongKind = "file"
+	SongKindLive SongKind = "live"
+)
+
+// kind returns the song's kind, defaulting to SongKindFile so callers that
+// predate the Kind field keep working.
+func (s *SongInfo) kind() SongKind {
+	if s.Kind == "" {
+		return SongKindFile
+	}
+	return s.Kind
+}
+
+// isHLSURL reports whether url points at an HLS playlist, based on its
+// extension (ignoring any query string or fragment).
+func isHLSURL(url string) bool {
+	u := url
+	if idx := strings.IndexAny(u, "?#"); idx >= 0 {
+		u = u[:idx]
+	}
+	return strings.HasSuffix(strings.ToLower(u), ".m3u8")
+}
+
+// NormalizationMode controls how loudness normalization is applied to a
+// track's audio before encoding.
+type NormalizationMode string
+
+const (
+	NormalizationOff         NormalizationMode = "off"
+	NormalizationTrack       NormalizationMode = "track"
+	NormalizationAlbum       NormalizationMode = "album"
+	NormalizationAutoMeasure NormalizationMode = "auto-measure"
 )
 
 type PlayState string
@@ -46,6 +133,20 @@ type SongInfo struct {
 	Artist   string `json:"artist"`
 	Duration int    `json:"duration"`
 	URL      string `json:"url"`
+
+	// Kind is "file" (default) for a normal on-demand track, or "live" for
+	// a continuous stream (Icecast/Shoutcast ICY or HLS). Live sources have
+	// no fixed duration, can't be seeked, and reconnect on drop instead of
+	// ending playback.
+	Kind SongKind `json:"kind,omitempty"`
+
+	// ReplayGain tags, in dB/dBFS per the ReplayGain spec. When present
+	// they're pushed into the pipeline via taginject so rgvolume can apply
+	// them; when absent, NormalizationAutoMeasure runs rganalysis instead.
+	ReplayGainTrackGain *float64 `json:"replaygain_track_gain,omitempty"`
+	ReplayGainTrackPeak *float64 `json:"replaygain_track_peak,omitempty"`
+	ReplayGainAlbumGain *float64 `json:"replaygain_album_gain,omitempty"`
+	ReplayGainAlbumPeak *float64 `json:"replaygain_album_peak,omitempty"`
 }
 
 type Player struct {
@@ -57,6 +158,16 @@ type Player struct {
 	currentSong *SongInfo
 	positionMs  int64
 	durationMs  int64
+	queue       []*SongInfo
+
+	// measuredGainDb is the rganalysis-measured track gain for the current
+	// song when NormalizationAutoMeasure had to run analysis for it.
+	measuredGainDb *float64
+
+	// volume is the user-facing target volume (0.0-1.0); volumeElem is the
+	// `volume` element of the currently active source bin it's applied to.
+	volume     float64
+	volumeElem *gst.Element
 
 	pipeline *gst.Pipeline
 	loop     *glib.MainLoop
@@ -66,6 +177,14 @@ type Player struct {
 
 	// Pause timeout: disconnect from room after 30s of pause
 	pauseTimer *time.Timer
+
+	// broadcastStart/broadcastStop are wired up by playbackLoop to add/
+	// remove a simulcast RTMP/Icecast tee branch on its pipeline; nil
+	// whenever no pipeline is running. broadcastState is the currently
+	// active broadcast's config, surfaced via /progress.
+	broadcastStart func(cfg BroadcastConfig) error
+	broadcastStop  func()
+	broadcastState *BroadcastConfig
 }
 
 type PlayerManager struct {
@@ -75,10 +194,13 @@ type PlayerManager struct {
 }
 
 type Config struct {
-	LiveKitURL    string
-	LiveKitAPIKey string
-	LiveKitSecret string
-	CallbackURL   string // Python backend callback URL
+	LiveKitURL        string
+	LiveKitAPIKey     string
+	LiveKitSecret     string
+	CallbackURL       string            // Python backend callback URL
+	NormalizationMode NormalizationMode // off / track / album / auto-measure
+	CrossfadeMs       int               // gapless queue transition overlap window
+	DJIdentities      []string          // participant identities allowed to send music-control messages
 }
 
 var manager *PlayerManager
@@ -101,6 +223,7 @@ func (pm *PlayerManager) GetOrCreatePlayer(roomName string) (*Player, error) {
 	p := &Player{
 		roomName: roomName,
 		state:    StateIdle,
+		volume:   DefaultVolume,
 	}
 
 	pm.players[roomName] = p
@@ -113,6 +236,20 @@ func (pm *PlayerManager) RemovePlayer(roomName string) {
 	delete(pm.players, roomName)
 }
 
+// isDJ reports whether identity is allowed to send music-control messages.
+// An empty allowlist (the default) accepts no inbound control at all.
+func (pm *PlayerManager) isDJ(identity string) bool {
+	if identity == "" {
+		return false
+	}
+	for _, id := range pm.config.DJIdentities {
+		if id == identity {
+			return true
+		}
+	}
+	return false
+}
+
 // notifySongEnded calls Python backend when a song finishes playing
 func notifySongEnded(roomName string) {
 	if manager == nil || manager.config.CallbackURL == "" {
@@ -142,6 +279,47 @@ func notifySongEnded(roomName string) {
 	}
 }
 
+// notifyStreamMetadata forwards a live source's ICY StreamTitle update to the
+// Python backend, same callback pattern as notifySongEnded. Most ICY
+// StreamTitle tags follow the "Artist - Title" convention; that's split out
+// on a best-effort basis, but raw is always forwarded untouched so the
+// backend can fall back to it when a stream doesn't follow the convention.
+func notifyStreamMetadata(roomName, raw string) {
+	if manager == nil || manager.config.CallbackURL == "" {
+		return
+	}
+
+	title, artist := raw, ""
+	if before, after, ok := strings.Cut(raw, " - "); ok {
+		artist, title = before, after
+	}
+
+	url := manager.config.CallbackURL + "/api/music/internal/stream-metadata"
+	payload := map[string]string{
+		"room_name": roomName,
+		"title":     title,
+		"artist":    artist,
+		"raw":       raw,
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to marshal stream metadata payload: %v", err)
+		return
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		log.Printf("Failed to notify stream metadata: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Stream metadata callback returned status: %d", resp.StatusCode)
+	}
+}
+
 func (p *Player) Connect() error {
 	p.mu.Lock()
 	if p.room != nil {
@@ -151,6 +329,16 @@ func (p *Player) Connect() error {
 	p.mu.Unlock()
 
 	cb := lksdk.NewRoomCallback()
+	cb.OnDataReceived = func(data []byte, params lksdk.DataReceiveParams) {
+		if params.Topic != DataTopicControl {
+			return
+		}
+		var identity string
+		if params.Sender != nil {
+			identity = params.Sender.Identity()
+		}
+		p.handleControlMessage(data, identity)
+	}
 	cb.OnDisconnected = func() {
 		log.Printf("Disconnected from room %s", p.roomName)
 		p.mu.Lock()
@@ -242,65 +430,131 @@ func (p *Player) Play() error {
 	return nil
 }
 
-// cleanupPlayback handles resource cleanup on error or completion
-func (p *Player) cleanupPlayback(pipeline *gst.Pipeline, room *lksdk.Room, pubSID string) {
-	if pipeline != nil {
-		pipeline.SetState(gst.StateNull)
-	}
-	if room != nil && pubSID != "" {
-		room.LocalParticipant.UnpublishTrack(pubSID)
-	}
-	p.mu.Lock()
-	p.pipeline = nil
-	p.loop = nil
-	p.mu.Unlock()
-}
+// buildSourceBin wires up the per-song decode chain - uridecodebin for a
+// regular file/URL or HLS track, souphttpsrc/icydemux/decodebin for a live
+// ICY stream - followed by audioconvert -> volume -> audioresample ->
+// capsfilter, inside its own bin with a ghost src pad. That lets it be
+// linked into and unlinked from the shared encode tail (mixer -> opusenc)
+// at runtime without tearing down the rest of the pipeline, which is what
+// makes gapless preload/swap possible. The *sourceresolver.Resolved it
+// returns is nil for live sources and SongKindFile sources sourceresolver
+// couldn't resolve; playbackLoop uses it to pick a byte-range vs. time-based
+// seek.
+func buildSourceBin(ctx context.Context, song *SongInfo, mode NormalizationMode, initialVolume float64) (*gst.Bin, *gst.Element, *float64, *sourceresolver.Resolved, error) {
+	bin := gst.NewBin(fmt.Sprintf("source-%s", song.Mid))
 
-// playbackLoop - copied from Ingress implementation with timeout protection
-func (p *Player) playbackLoop(song *SongInfo, startPosMs int64) {
-	log.Printf("Starting playback: %s from %dms", song.Name, startPosMs)
+	audioconvert, _ := gst.NewElement("audioconvert")
 
-	// Overall timeout: song duration + 60 seconds buffer for loading
-	maxDuration := time.Duration(song.Duration)*time.Second + 60*time.Second
-	if maxDuration < 2*time.Minute {
-		maxDuration = 2 * time.Minute
+	linkFirstAudioPad := func(sinkPad *gst.Pad) func(self *gst.Element, pad *gst.Pad) {
+		return func(self *gst.Element, pad *gst.Pad) {
+			if sinkPad.IsLinked() {
+				return
+			}
+			padCaps := pad.GetCurrentCaps()
+			if padCaps == nil {
+				return
+			}
+			structure := padCaps.GetStructureAt(0)
+			if structure == nil {
+				return
+			}
+			name := structure.Name()
+			if len(name) >= 5 && name[:5] == "audio" {
+				pad.Link(sinkPad)
+			}
+		}
 	}
-	overallTimeout := time.AfterFunc(maxDuration, func() {
-		log.Printf("Playback timeout for %s, forcing cleanup", song.Name)
-		p.mu.Lock()
-		if p.cancel != nil {
-			p.cancel()
+
+	var decodeElems []*gst.Element
+	var resolved *sourceresolver.Resolved
+	switch song.kind() {
+	case SongKindLive:
+		if isHLSURL(song.URL) {
+			// uridecodebin autoplugs hlsdemux for .m3u8 URIs same as it
+			// would any other container, so the HLS path reuses it as-is.
+			uridecodebin, err := gst.NewElement("uridecodebin")
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("failed to create uridecodebin: %w", err)
+			}
+			uridecodebin.Set("uri", song.URL)
+			uridecodebin.Connect("pad-added", linkFirstAudioPad(audioconvert.GetStaticPad("sink")))
+			decodeElems = []*gst.Element{uridecodebin}
+		} else {
+			// Icecast/Shoutcast ICY stream: souphttpsrc in iradio mode
+			// surfaces the ICY-MetaData headers, icydemux strips them off
+			// the stream and emits them as tags (including StreamTitle),
+			// and decodebin handles whatever codec the stream carries
+			// (mp3/aac/etc).
+			souphttpsrc, err := gst.NewElement("souphttpsrc")
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("failed to create souphttpsrc: %w", err)
+			}
+			souphttpsrc.Set("location", song.URL)
+			souphttpsrc.Set("is-live", true)
+			souphttpsrc.Set("iradio-mode", true)
+
+			icydemux, err := gst.NewElement("icydemux")
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("failed to create icydemux: %w", err)
+			}
+			decodebin, err := gst.NewElement("decodebin")
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("failed to create decodebin: %w", err)
+			}
+
+			icydemux.Connect("pad-added", func(self *gst.Element, pad *gst.Pad) {
+				sinkPad := decodebin.GetStaticPad("sink")
+				if !sinkPad.IsLinked() {
+					pad.Link(sinkPad)
+				}
+			})
+			decodebin.Connect("pad-added", linkFirstAudioPad(audioconvert.GetStaticPad("sink")))
+
+			gst.ElementLinkMany(souphttpsrc, icydemux)
+			decodeElems = []*gst.Element{souphttpsrc, icydemux, decodebin}
 		}
-		if p.loop != nil {
-			p.loop.Quit()
+	default: // SongKindFile
+		// Sniff the source up front so an unsupported format is rejected
+		// before we ever stand up a pipeline for it, and so file:// / s3://
+		// URLs get mapped to something uridecodebin can open directly.
+		resolvedURI := song.URL
+		expectedDuration := time.Duration(song.Duration) * time.Second
+		r, resolveErr := sourceresolver.Resolve(ctx, song.URL, expectedDuration)
+		if resolveErr != nil {
+			if errors.Is(resolveErr, sourceresolver.ErrUnsupportedFormat) {
+				return nil, nil, nil, nil, fmt.Errorf("unsupported source format for %s: %w", song.URL, resolveErr)
+			}
+			log.Printf("Source resolution failed for %s, falling back to direct URI: %v", song.Name, resolveErr)
+		} else {
+			resolvedURI = r.URI
+			resolved = r
+			if r.SampleRate > 0 && (r.SampleRate != SampleRate || r.Channels != Channels) {
+				log.Printf("Source %s is %dHz/%dch, audioresample/audioconvert will convert to %dHz/%dch", song.Name, r.SampleRate, r.Channels, SampleRate, Channels)
+			}
+			if r.VBRSuspected {
+				log.Printf("Source %s looks VBR-encoded, byte-range seeks will be approximate", song.Name)
+			}
 		}
-		p.state = StateStopped
-		p.mu.Unlock()
-	})
-	defer overallTimeout.Stop()
 
-	// Build GStreamer pipeline exactly like Ingress
-	// uridecodebin -> audioconvert -> audioresample -> capsfilter -> opusenc -> appsink
-	pipeline, err := gst.NewPipeline("music-pipeline")
-	if err != nil {
-		log.Printf("Failed to create pipeline: %v", err)
-		p.mu.Lock()
-		p.state = StateIdle
-		p.mu.Unlock()
-		return
+		uridecodebin, err := gst.NewElement("uridecodebin")
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("failed to create uridecodebin: %w", err)
+		}
+		uridecodebin.Set("uri", resolvedURI)
+		uridecodebin.Set("buffer-size", 2*1024*1024) // 2MB buffer
+		uridecodebin.Set("download", true)           // Enable download buffering
+		uridecodebin.Connect("pad-added", linkFirstAudioPad(audioconvert.GetStaticPad("sink")))
+		decodeElems = []*gst.Element{uridecodebin}
 	}
 
-	// Create elements - exactly like Ingress output.go
-	uridecodebin, _ := gst.NewElement("uridecodebin")
-	uridecodebin.Set("uri", song.URL)
-	// Set buffer size and timeout for network streams
-	uridecodebin.Set("buffer-size", 2*1024*1024) // 2MB buffer
-	uridecodebin.Set("download", true)           // Enable download buffering
+	// Volume control - lets /volume and the Play/Pause/Stop/Seek fades
+	// animate this bin's level independently of any other bin sharing the
+	// mixer (e.g. during a gapless crossfade).
+	volumeElem, _ := gst.NewElement("volume")
+	volumeElem.Set("volume", initialVolume)
 
-	audioconvert, _ := gst.NewElement("audioconvert")
 	audioresample, _ := gst.NewElement("audioresample")
 
-	// Capsfilter - exactly like Ingress
 	capsfilter, _ := gst.NewElement("capsfilter")
 	caps := gst.NewCapsFromString(fmt.Sprintf(
 		"audio/x-raw,format=S16LE,layout=interleaved,rate=%d,channels=%d",
@@ -308,162 +562,938 @@ func (p *Player) playbackLoop(song *SongInfo, startPosMs int64) {
 	))
 	capsfilter.Set("caps", caps)
 
-	// Opus encoder - optimized for music (not voice)
-	opusenc, _ := gst.NewElement("opusenc")
-	opusenc.Set("bitrate", OpusBitrate)
-	opusenc.Set("frame-size", OpusFrameSize)
-	opusenc.Set("audio-type", 2049)  // generic (music), not voice
-	opusenc.Set("dtx", false)        // disable DTX for music quality
+	// Loudness normalization: rgvolume/rglimiter sit between audioresample
+	// and capsfilter, fed either by the song's own ReplayGain tags or by a
+	// one-shot rganalysis pass when NormalizationAutoMeasure is on and no
+	// tags were supplied.
+	chain := []*gst.Element{audioconvert, volumeElem, audioresample}
+	var measuredGain *float64
+	if mode != NormalizationOff && song.kind() != SongKindLive {
+		haveTags := song.ReplayGainTrackGain != nil || song.ReplayGainAlbumGain != nil
+
+		gainTags := map[string]float64{}
+		if haveTags {
+			if song.ReplayGainTrackGain != nil {
+				gainTags["replaygain-track-gain"] = *song.ReplayGainTrackGain
+			}
+			if song.ReplayGainTrackPeak != nil {
+				gainTags["replaygain-track-peak"] = *song.ReplayGainTrackPeak
+			}
+			if song.ReplayGainAlbumGain != nil {
+				gainTags["replaygain-album-gain"] = *song.ReplayGainAlbumGain
+			}
+			if song.ReplayGainAlbumPeak != nil {
+				gainTags["replaygain-album-peak"] = *song.ReplayGainAlbumPeak
+			}
+		} else if mode == NormalizationAutoMeasure {
+			gain, peak, measureErr := measureReplayGain(ctx, song)
+			if measureErr != nil {
+				log.Printf("ReplayGain analysis failed for %s, skipping normalization: %v", song.Mid, measureErr)
+			} else {
+				gainTags["replaygain-track-gain"] = gain
+				gainTags["replaygain-track-peak"] = peak
+				measuredGain = &gain
+			}
+		}
 
-	// Appsink - exactly like Ingress
-	appsinkElem, _ := gst.NewElement("appsink")
-	appsink := app.SinkFromElement(appsinkElem)
-	appsink.SetProperty("emit-signals", true)
-	appsink.SetProperty("sync", true)
+		if len(gainTags) > 0 {
+			taginject, _ := gst.NewElement("taginject")
+			taginject.Set("tags", buildReplayGainTagString(gainTags))
 
-	// Add all elements to pipeline
-	pipeline.AddMany(uridecodebin, audioconvert, audioresample, capsfilter, opusenc, appsinkElem)
+			rgvolume, _ := gst.NewElement("rgvolume")
+			rgvolume.Set("album-mode", mode == NormalizationAlbum)
 
-	// Link static elements
-	gst.ElementLinkMany(audioconvert, audioresample, capsfilter, opusenc, appsinkElem)
+			rglimiter, _ := gst.NewElement("rglimiter")
 
-	// Handle dynamic pad from uridecodebin
-	uridecodebin.Connect("pad-added", func(self *gst.Element, pad *gst.Pad) {
-		sinkPad := audioconvert.GetStaticPad("sink")
-		if sinkPad.IsLinked() {
-			return
+			chain = append(chain, taginject, rgvolume, rglimiter)
 		}
-		padCaps := pad.GetCurrentCaps()
-		if padCaps == nil {
-			return
-		}
-		structure := padCaps.GetStructureAt(0)
-		if structure == nil {
-			return
-		}
-		name := structure.Name()
-		if len(name) >= 5 && name[:5] == "audio" {
-			pad.Link(sinkPad)
-		}
-	})
+	}
+	chain = append(chain, capsfilter)
 
-	p.mu.Lock()
-	p.pipeline = pipeline
-	p.mu.Unlock()
+	bin.AddMany(append(append([]*gst.Element{}, decodeElems...), chain...)...)
+	gst.ElementLinkMany(chain...)
 
-	// Create LocalSampleTrack - exactly like Ingress lksdk_output.go
-	track, err := lksdk.NewLocalSampleTrack(webrtc.RTPCodecCapability{
-		MimeType:  webrtc.MimeTypeOpus,
-		ClockRate: SampleRate,
-		Channels:  Channels,
-	})
-	if err != nil {
-		log.Printf("Failed to create track: %v", err)
-		p.cleanupPlayback(pipeline, nil, "")
-		p.mu.Lock()
-		p.state = StateIdle
-		p.mu.Unlock()
-		return
-	}
+	ghostPad := gst.NewGhostPad("src", capsfilter.GetStaticPad("src"))
+	bin.AddPad(ghostPad.Pad)
 
-	p.mu.Lock()
-	if p.room == nil {
-		p.mu.Unlock()
-		log.Printf("Room not connected")
-		p.cleanupPlayback(pipeline, nil, "")
-		p.mu.Lock()
-		p.state = StateIdle
-		p.mu.Unlock()
+	return bin, volumeElem, measuredGain, resolved, nil
+}
+
+// animateVolume ramps a GStreamer `volume` element's "volume" property from
+// `from` to `to` over durationMs on a ticker, so level changes don't click.
+// A non-positive durationMs jumps straight to the target. Returns early if
+// ctx is cancelled mid-ramp, leaving the property at whatever it last hit.
+func animateVolume(ctx context.Context, elem *gst.Element, from, to float64, durationMs int) {
+	if durationMs <= 0 {
+		elem.Set("volume", to)
 		return
 	}
-	room := p.room
-	p.track = track
-	p.mu.Unlock()
 
-	// Publish track with music-optimized settings
-	pub, err := room.LocalParticipant.PublishTrack(track, &lksdk.TrackPublicationOptions{
-		Name:       "music",
-		Source:     livekit.TrackSource_MICROPHONE,
-		DisableDTX: true,  // critical for music quality
-		Stereo:     true,  // enable stereo
-	})
-	if err != nil {
-		log.Printf("Failed to publish track: %v", err)
-		p.cleanupPlayback(pipeline, nil, "")
-		p.mu.Lock()
-		p.state = StateIdle
-		p.mu.Unlock()
-		return
+	steps := durationMs / int(volumeFadeStepInterval/time.Millisecond)
+	if steps < 1 {
+		steps = 1
 	}
-	log.Printf("Published track: %s", pub.SID())
-	pubSID := pub.SID()
 
-	// Handle samples from appsink - exactly like Ingress output.go handleSample
-	appsink.SetCallbacks(&app.SinkCallbacks{
-		EOSFunc: func(sink *app.Sink) {
-			log.Printf("EOS received")
-		},
-		NewSampleFunc: func(sink *app.Sink) gst.FlowReturn {
-			sample := sink.PullSample()
-			if sample == nil {
-				return gst.FlowEOS
-			}
+	ticker := time.NewTicker(volumeFadeStepInterval)
+	defer ticker.Stop()
 
-			buffer := sample.GetBuffer()
-			if buffer == nil {
-				return gst.FlowError
-			}
+	for i := 1; i <= steps; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			frac := float64(i) / float64(steps)
+			elem.Set("volume", from+(to-from)*frac)
+		}
+	}
+}
 
-			duration := time.Duration(buffer.Duration())
+// armCrossfadeRamp binds a GstController InterpolationControlSource to
+// elem's "volume" property, ramping from `from` to `to` over durationMs on
+// the pipeline clock rather than animateVolume's wall-clock ticker. A plain
+// fade-in/out only has to sound smooth against itself, so ticker jitter is
+// inaudible; a crossfade mixes the outgoing and incoming bins sample-for-
+// sample through the same mixer, so both ramps need to land on the same
+// clock timeline or the overlap beats/clicks. Returns the binding so the
+// caller can remove it once the ramp finishes.
+func armCrossfadeRamp(pipeline *gst.Pipeline, elem *gst.Element, from, to float64, durationMs int) gst.ControlBinding {
+	source := gst.NewInterpolationControlSource()
+	source.SetProperty("mode", gst.InterpolationModeLinear)
+
+	runningTime := uint64(pipeline.GetPipelineClock().GetTime() - pipeline.GetBaseTime())
+	source.Set(runningTime, from)
+	source.Set(runningTime+uint64(durationMs)*uint64(time.Millisecond), to)
+
+	binding := gst.NewDirectControlBinding(elem, "volume", source)
+	elem.AddControlBinding(binding)
+	return binding
+}
 
-			// WriteSample - exactly like Ingress
-			err := track.WriteSample(media.Sample{
-				Data:     buffer.Bytes(),
-				Duration: duration,
-			}, nil)
-			if err != nil {
-				log.Printf("WriteSample error: %v", err)
-			}
+// buildReplayGainTagString renders a GStreamer tag string (as consumed by
+// the taginject "tags" property) from the ReplayGain key/value pairs.
+func buildReplayGainTagString(tags map[string]float64) string {
+	s := ""
+	for key, val := range tags {
+		if s != "" {
+			s += ","
+		}
+		s += fmt.Sprintf("%s=(double)%f", key, val)
+	}
+	return s
+}
 
-			// Update position
-			p.mu.Lock()
-			p.positionMs += int64(duration / time.Millisecond)
-			if p.positionMs > p.durationMs {
-				p.positionMs = p.durationMs
-			}
-			p.mu.Unlock()
+// BroadcastMode selects the protocol a simulcast broadcast branch streams
+// out over.
+type BroadcastMode string
 
-			return gst.FlowOK
-		},
-	})
+const (
+	BroadcastModeRTMP    BroadcastMode = "rtmp"
+	BroadcastModeIcecast BroadcastMode = "icecast"
+)
 
-	// Seek if needed
-	if startPosMs > 0 {
-		pipeline.SetState(gst.StatePaused)
-		pipeline.Bin.Element.GetState(gst.StateNull, gst.ClockTimeNone)
-		pipeline.Bin.Element.SeekSimple(int64(startPosMs)*int64(time.Millisecond), gst.FormatTime, gst.SeekFlagFlush|gst.SeekFlagKeyUnit)
-	}
+// BroadcastConfig describes a simulcast RTMP/Icecast target tee'd off the
+// room's encoded audio alongside the LiveKit publish.
+type BroadcastConfig struct {
+	Mode BroadcastMode `json:"mode"`
+	// URL is the RTMP target ("rtmp://host/app/key") for BroadcastModeRTMP,
+	// or the Icecast server address ("host:port") for BroadcastModeIcecast.
+	URL string `json:"url"`
+	// Mount and StreamName only apply to BroadcastModeIcecast.
+	Mount      string `json:"mount,omitempty"`
+	StreamName string `json:"stream_name,omitempty"`
+}
 
-	// Start pipeline with loading timeout
-	loadingTimeout := time.AfterFunc(30*time.Second, func() {
-		log.Printf("Loading timeout for %s", song.Name)
-		p.mu.Lock()
-		if p.state == StateLoading || p.state == StatePlaying {
-			if p.cancel != nil {
-				p.cancel()
-			}
-			if p.loop != nil {
-				p.loop.Quit()
-			}
-			p.state = StateStopped
+// buildBroadcastSinkBin wires up the mux+protocol-sink chain for a
+// simulcast broadcast branch - flvmux/rtmpsink for RTMP, oggmux/shout2send
+// for Icecast - behind a ghost sink pad, so it can be teed on and off the
+// pipeline's shared opusenc output at runtime.
+func buildBroadcastSinkBin(cfg BroadcastConfig) (*gst.Bin, error) {
+	bin := gst.NewBin("broadcast-sink")
+
+	queueElem, _ := gst.NewElement("queue")
+
+	var tail []*gst.Element
+	switch cfg.Mode {
+	case BroadcastModeRTMP:
+		flvmux, err := gst.NewElement("flvmux")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create flvmux: %w", err)
 		}
-		p.mu.Unlock()
-	})
+		flvmux.Set("streamable", true)
 
-	pipeline.SetState(gst.StatePlaying)
+		rtmpsink, err := gst.NewElement("rtmpsink")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create rtmpsink: %w", err)
+		}
+		rtmpsink.Set("location", cfg.URL)
+
+		tail = []*gst.Element{flvmux, rtmpsink}
+	case BroadcastModeIcecast:
+		oggmux, err := gst.NewElement("oggmux")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create oggmux: %w", err)
+		}
+
+		shout2send, err := gst.NewElement("shout2send")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create shout2send: %w", err)
+		}
+		shout2send.Set("ip", cfg.URL)
+		shout2send.Set("mount", cfg.Mount)
+		if cfg.StreamName != "" {
+			shout2send.Set("streamname", cfg.StreamName)
+		}
+
+		tail = []*gst.Element{oggmux, shout2send}
+	default:
+		return nil, fmt.Errorf("unknown broadcast mode: %q", cfg.Mode)
+	}
+
+	chain := append([]*gst.Element{queueElem}, tail...)
+	bin.AddMany(chain...)
+	gst.ElementLinkMany(chain...)
+
+	ghostPad := gst.NewGhostPad("sink", queueElem.GetStaticPad("sink"))
+	bin.AddPad(ghostPad.Pad)
+
+	return bin, nil
+}
+
+// replayGainCache holds measured ReplayGain results keyed by Song.Mid so
+// NormalizationAutoMeasure only has to analyze a given song once.
+var replayGainCache sync.Map // map[string]replayGainMeasurement
+
+type replayGainMeasurement struct {
+	gain float64
+	peak float64
+}
+
+// measureReplayGain runs a one-shot decode-only pipeline through rganalysis
+// to compute a track's ReplayGain, caching the result by Mid. The analysis
+// bus watch runs on its own GMainContext rather than the process-default
+// one: buildSourceBin can reach this from the startGaplessPreload goroutine
+// while the playback loop's own main loop is already iterating the default
+// context, and a second watch on that same context never gets a turn - the
+// preload goroutine would stall forever waiting on EOS/error. ctx is honored
+// so a Stop/Seek that cancels playback mid-analysis doesn't hang either.
+func measureReplayGain(ctx context.Context, song *SongInfo) (gain float64, peak float64, err error) {
+	if cached, ok := replayGainCache.Load(song.Mid); ok {
+		m := cached.(replayGainMeasurement)
+		return m.gain, m.peak, nil
+	}
+
+	pipeline, err := gst.NewPipeline(fmt.Sprintf("rg-analysis-%s", song.Mid))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create analysis pipeline: %w", err)
+	}
+	defer pipeline.SetState(gst.StateNull)
+
+	resolvedURI := song.URL
+	expectedDuration := time.Duration(song.Duration) * time.Second
+	if resolved, resolveErr := sourceresolver.Resolve(ctx, song.URL, expectedDuration); resolveErr != nil {
+		log.Printf("Source resolution failed for %s, falling back to direct URI: %v", song.Name, resolveErr)
+	} else {
+		resolvedURI = resolved.URI
+	}
+
+	uridecodebin, _ := gst.NewElement("uridecodebin")
+	uridecodebin.Set("uri", resolvedURI)
+	audioconvert, _ := gst.NewElement("audioconvert")
+	audioresample, _ := gst.NewElement("audioresample")
+	rganalysis, _ := gst.NewElement("rganalysis")
+	rganalysis.Set("forced", true)
+	fakesink, _ := gst.NewElement("fakesink")
+
+	pipeline.AddMany(uridecodebin, audioconvert, audioresample, rganalysis, fakesink)
+	gst.ElementLinkMany(audioconvert, audioresample, rganalysis, fakesink)
+
+	uridecodebin.Connect("pad-added", func(self *gst.Element, pad *gst.Pad) {
+		sinkPad := audioconvert.GetStaticPad("sink")
+		if sinkPad.IsLinked() {
+			return
+		}
+		pad.Link(sinkPad)
+	})
+
+	pipeline.SetState(gst.StatePlaying)
+
+	analysisCtx := glib.NewMainContext()
+	analysisCtx.PushThreadDefault()
+	defer analysisCtx.PopThreadDefault()
+	loop := glib.NewMainLoop(analysisCtx, false)
+
+	var analysisErr error
+	bus := pipeline.GetPipelineBus()
+	bus.AddWatch(func(msg *gst.Message) bool {
+		switch msg.Type() {
+		case gst.MessageTag:
+			tagList := msg.ParseTag()
+			if g, ok := tagList.GetDouble("replaygain-track-gain"); ok {
+				gain = g
+			}
+			if pk, ok := tagList.GetDouble("replaygain-track-peak"); ok {
+				peak = pk
+			}
+		case gst.MessageError:
+			analysisErr = msg.ParseError()
+			loop.Quit()
+			return false
+		case gst.MessageEOS:
+			loop.Quit()
+			return false
+		}
+		return true
+	})
+
+	if ctx != nil {
+		stopWatch := make(chan struct{})
+		defer close(stopWatch)
+		go func() {
+			select {
+			case <-ctx.Done():
+				loop.Quit()
+			case <-stopWatch:
+			}
+		}()
+	}
+
+	loop.Run()
+
+	if ctx != nil && ctx.Err() != nil {
+		return 0, 0, fmt.Errorf("replaygain analysis for %s cancelled: %w", song.Mid, ctx.Err())
+	}
+	if analysisErr != nil {
+		return 0, 0, fmt.Errorf("replaygain analysis for %s failed: %v", song.Mid, analysisErr)
+	}
+
+	replayGainCache.Store(song.Mid, replayGainMeasurement{gain: gain, peak: peak})
+	return gain, peak, nil
+}
+
+// gaplessPreload tracks the state of a prerolled "next" source bin that has
+// been spun up ahead of the current track ending.
+type gaplessPreload struct {
+	mu          sync.Mutex
+	bin         *gst.Bin
+	song        *SongInfo
+	volumeElem  *gst.Element
+	gain        *float64
+	ready       bool
+	started     bool
+	crossfading bool
+}
+
+func (gp *gaplessPreload) markStarted() bool {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	if gp.started {
+		return false
+	}
+	gp.started = true
+	return true
+}
+
+func (gp *gaplessPreload) fail() {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	gp.started = false
+}
+
+// stash records the built bin/volume element/measured gain as soon as
+// buildSourceBin succeeds, ahead of the (blocking) preroll finishing.
+func (gp *gaplessPreload) stash(bin *gst.Bin, song *SongInfo, volumeElem *gst.Element, gain *float64) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	gp.bin = bin
+	gp.song = song
+	gp.volumeElem = volumeElem
+	gp.gain = gain
+}
+
+func (gp *gaplessPreload) setReady() {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	gp.ready = true
+}
+
+// markCrossfading flips crossfading on exactly once, so the crossfade
+// window is only ever entered a single time per preloaded bin.
+func (gp *gaplessPreload) markCrossfading() bool {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	if gp.crossfading {
+		return false
+	}
+	gp.crossfading = true
+	return true
+}
+
+func (gp *gaplessPreload) take() (bin *gst.Bin, song *SongInfo, volumeElem *gst.Element, gain *float64, ready bool) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	return gp.bin, gp.song, gp.volumeElem, gp.gain, gp.ready
+}
+
+// cleanupPlayback handles resource cleanup on error or completion
+func (p *Player) cleanupPlayback(pipeline *gst.Pipeline, room *lksdk.Room, pubSID string) {
+	if pipeline != nil {
+		pipeline.SetState(gst.StateNull)
+	}
+	if room != nil && pubSID != "" {
+		room.LocalParticipant.UnpublishTrack(pubSID)
+	}
+	p.mu.Lock()
+	p.pipeline = nil
+	p.loop = nil
+	p.broadcastStart = nil
+	p.broadcastStop = nil
+	p.broadcastState = nil
+	p.mu.Unlock()
+}
+
+// playbackLoop - copied from Ingress implementation with timeout protection
+func (p *Player) playbackLoop(song *SongInfo, startPosMs int64) {
+	log.Printf("Starting playback: %s from %dms", song.Name, startPosMs)
+
+	// Overall timeout: song duration + 60 seconds buffer for loading. Live
+	// streams have no fixed duration and run until Stop or a fatal error,
+	// so they don't get one. Gapless swaps keep reusing this same loop, so
+	// armOverallTimeout is called again for each new current song instead
+	// of sizing the watchdog once off the first song in the queue.
+	var overallTimeout *time.Timer
+	armOverallTimeout := func(forSong *SongInfo) {
+		if overallTimeout != nil {
+			overallTimeout.Stop()
+			overallTimeout = nil
+		}
+		if forSong.kind() == SongKindLive {
+			return
+		}
+		maxDuration := time.Duration(forSong.Duration)*time.Second + 60*time.Second
+		if maxDuration < 2*time.Minute {
+			maxDuration = 2 * time.Minute
+		}
+		overallTimeout = time.AfterFunc(maxDuration, func() {
+			log.Printf("Playback timeout for %s, forcing cleanup", forSong.Name)
+			p.mu.Lock()
+			if p.cancel != nil {
+				p.cancel()
+			}
+			if p.loop != nil {
+				p.loop.Quit()
+			}
+			p.state = StateStopped
+			p.mu.Unlock()
+		})
+	}
+	armOverallTimeout(song)
+	defer func() {
+		if overallTimeout != nil {
+			overallTimeout.Stop()
+		}
+	}()
+
+	// Build GStreamer pipeline exactly like Ingress, except the per-song
+	// decode chain lives in a swappable bin so the next track can be
+	// prerolled and linked in ahead of EOS for gapless playback. The two
+	// bins feed a persistent audiomixer so an outgoing/incoming pair can
+	// overlap for a crossfade instead of a hard cut.
+	// source-bin(uridecodebin -> audioconvert -> volume -> audioresample -> capsfilter) -> mixer -> opusenc -> tee -> queue -> appsink
+	pipeline, err := gst.NewPipeline("music-pipeline")
+	if err != nil {
+		log.Printf("Failed to create pipeline: %v", err)
+		p.mu.Lock()
+		p.state = StateIdle
+		p.mu.Unlock()
+		return
+	}
+
+	normalizationMode := manager.config.NormalizationMode
+	p.mu.RLock()
+	targetVolume := p.volume
+	p.mu.RUnlock()
+
+	activeBin, activeVolumeElem, measuredGain, activeResolved, err := buildSourceBin(p.ctx, song, normalizationMode, 0)
+	if err != nil {
+		log.Printf("Failed to build source bin: %v", err)
+		p.mu.Lock()
+		p.state = StateIdle
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Lock()
+	p.measuredGainDb = measuredGain
+	p.volumeElem = activeVolumeElem
+	p.mu.Unlock()
+
+	// activeMu guards activeBin/activeVolumeElem, which are read and
+	// written from the glib main loop thread (bus watch, reconnectLive's
+	// idle callback) and from the startCrossfade goroutine as gapless swaps
+	// happen.
+	var activeMu sync.Mutex
+	getActive := func() (*gst.Bin, *gst.Element) {
+		activeMu.Lock()
+		defer activeMu.Unlock()
+		return activeBin, activeVolumeElem
+	}
+	setActive := func(bin *gst.Bin, volumeElem *gst.Element) {
+		activeMu.Lock()
+		defer activeMu.Unlock()
+		activeBin, activeVolumeElem = bin, volumeElem
+	}
+
+	// Mixer - shared by every source bin across gapless swaps, so a
+	// crossfade can have two bins feeding it at once.
+	mixer, _ := gst.NewElement("audiomixer")
+
+	// Opus encoder - optimized for music (not voice)
+	opusenc, _ := gst.NewElement("opusenc")
+	opusenc.Set("bitrate", OpusBitrate)
+	opusenc.Set("frame-size", OpusFrameSize)
+	opusenc.Set("audio-type", 2049)  // generic (music), not voice
+	opusenc.Set("dtx", false)        // disable DTX for music quality
+
+	// Tee after opusenc so a simulcast RTMP/Icecast branch can be added
+	// and removed at runtime (via /broadcast/start and /broadcast/stop)
+	// without disturbing the LiveKit appsink branch.
+	tee, _ := gst.NewElement("tee")
+	appsinkQueue, _ := gst.NewElement("queue")
+
+	// Appsink - exactly like Ingress
+	appsinkElem, _ := gst.NewElement("appsink")
+	appsink := app.SinkFromElement(appsinkElem)
+	appsink.SetProperty("emit-signals", true)
+	appsink.SetProperty("sync", true)
+
+	// Add elements to pipeline and link the shared encode tail
+	pipeline.AddMany(activeBin, mixer, opusenc, tee, appsinkQueue, appsinkElem)
+	gst.ElementLinkMany(mixer, opusenc, tee)
+	gst.ElementLinkMany(appsinkQueue, appsinkElem)
+	tee.GetRequestPad("src_%u").Link(appsinkQueue.GetStaticPad("sink"))
+	activeBin.GetStaticPad("src").Link(mixer.GetRequestPad("sink_%u"))
+
+	// startBroadcast/stopBroadcast splice a simulcast sink branch on/off
+	// the tee's request pads. The add/remove itself happens inside a
+	// blocking pad probe so the tee never forwards a buffer into a
+	// half-linked or half-torn-down branch.
+	var broadcastMu sync.Mutex
+	var broadcastBin *gst.Bin
+	var broadcastTeePad *gst.Pad
+
+	startBroadcast := func(cfg BroadcastConfig) error {
+		broadcastMu.Lock()
+		defer broadcastMu.Unlock()
+		if broadcastBin != nil {
+			return fmt.Errorf("broadcast already running for this room")
+		}
+
+		sinkBin, err := buildBroadcastSinkBin(cfg)
+		if err != nil {
+			return err
+		}
+		pipeline.Add(sinkBin)
+		sinkBin.SyncStateWithParent()
+
+		teeSrcPad := tee.GetRequestPad("src_%u")
+		teeSrcPad.AddProbe(gst.PadProbeTypeBlock, func(pad *gst.Pad, info *gst.PadProbeInfo) gst.PadProbeReturn {
+			teeSrcPad.Link(sinkBin.GetStaticPad("sink"))
+			return gst.PadProbeRemove
+		})
+
+		broadcastBin = sinkBin
+		broadcastTeePad = teeSrcPad
+		return nil
+	}
+
+	stopBroadcast := func() {
+		broadcastMu.Lock()
+		defer broadcastMu.Unlock()
+		if broadcastBin == nil {
+			return
+		}
+		sinkBin := broadcastBin
+		teeSrcPad := broadcastTeePad
+		broadcastBin = nil
+		broadcastTeePad = nil
+
+		teeSrcPad.AddProbe(gst.PadProbeTypeBlock, func(pad *gst.Pad, info *gst.PadProbeInfo) gst.PadProbeReturn {
+			teeSrcPad.Unlink(sinkBin.GetStaticPad("sink"))
+			tee.ReleaseRequestPad(teeSrcPad)
+			sinkBin.SetState(gst.StateNull)
+			pipeline.Remove(sinkBin)
+			return gst.PadProbeRemove
+		})
+	}
 
-	// Main loop
-	loop := glib.NewMainLoop(glib.MainContextDefault(), false)
+	p.mu.Lock()
+	p.pipeline = pipeline
+	p.broadcastStart = startBroadcast
+	p.broadcastStop = stopBroadcast
+	p.mu.Unlock()
+
+	// Create LocalSampleTrack - exactly like Ingress lksdk_output.go
+	track, err := lksdk.NewLocalSampleTrack(webrtc.RTPCodecCapability{
+		MimeType:  webrtc.MimeTypeOpus,
+		ClockRate: SampleRate,
+		Channels:  Channels,
+	})
+	if err != nil {
+		log.Printf("Failed to create track: %v", err)
+		p.cleanupPlayback(pipeline, nil, "")
+		p.mu.Lock()
+		p.state = StateIdle
+		p.mu.Unlock()
+		return
+	}
+
+	p.mu.Lock()
+	if p.room == nil {
+		p.mu.Unlock()
+		log.Printf("Room not connected")
+		p.cleanupPlayback(pipeline, nil, "")
+		p.mu.Lock()
+		p.state = StateIdle
+		p.mu.Unlock()
+		return
+	}
+	room := p.room
+	p.track = track
+	p.mu.Unlock()
+
+	// Publish track with music-optimized settings
+	pub, err := room.LocalParticipant.PublishTrack(track, &lksdk.TrackPublicationOptions{
+		Name:       "music",
+		Source:     livekit.TrackSource_MICROPHONE,
+		DisableDTX: true,  // critical for music quality
+		Stereo:     true,  // enable stereo
+	})
+	if err != nil {
+		log.Printf("Failed to publish track: %v", err)
+		p.cleanupPlayback(pipeline, nil, "")
+		p.mu.Lock()
+		p.state = StateIdle
+		p.mu.Unlock()
+		return
+	}
+	log.Printf("Published track: %s", pub.SID())
+	pubSID := pub.SID()
+
+	preload := &gaplessPreload{}
+
+	// startGaplessPreload builds and prerolls the next queued song's source
+	// bin ahead of time, so it can be swapped in on EOS with no gap and no
+	// track re-publish. Runs in its own goroutine since GetState blocks.
+	startGaplessPreload := func(next *SongInfo) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Gapless preload panic for %s: %v", next.Name, r)
+				preload.fail()
+			}
+		}()
+
+		bin, volumeElem, nextGain, _, err := buildSourceBin(p.ctx, next, normalizationMode, 0)
+		if err != nil {
+			log.Printf("Gapless preload failed for %s: %v", next.Name, err)
+			preload.fail()
+			return
+		}
+		preload.stash(bin, next, volumeElem, nextGain)
+
+		pipeline.Add(bin)
+		bin.SetState(gst.StatePaused)
+		// Block until the bin's async preroll settles, same idiom used
+		// below for the initial seek-before-play.
+		bin.Element.GetState(gst.StateNull, gst.ClockTimeNone)
+
+		select {
+		case <-p.ctx.Done():
+			bin.SetState(gst.StateNull)
+			pipeline.Remove(bin)
+			preload.fail()
+			return
+		default:
+		}
+
+		preload.setReady()
+		log.Printf("Preloaded next track for gapless playback: %s", next.Name)
+	}
+
+	crossfadeMs := manager.config.CrossfadeMs
+	if crossfadeMs <= 0 {
+		crossfadeMs = DefaultCrossfadeMs
+	}
+
+	// startCrossfade links the prerolled next bin into the mixer and ramps
+	// its volume up from 0 while ramping the outgoing bin's volume down to
+	// 0, over crossfadeMs. Both ramps are scheduled on the pipeline clock via
+	// armCrossfadeRamp rather than animateVolume's ticker, so the overlap
+	// stays sample-accurate instead of drifting with goroutine scheduling
+	// jitter. Both bins play concurrently through the mixer for that window,
+	// which is what avoids the audible gap/click a hard cut would have.
+	// Once the fade completes it finalizes the swap itself
+	// (removes the outgoing bin, releases its mixer pad, advances
+	// currentSong/position/duration, pops the queue, resets preload)
+	// instead of waiting on a pipeline EOS: audiomixer only forwards EOS
+	// once every sink pad is EOS, so with the incoming bin still live that
+	// message never arrives.
+	startCrossfade := func(nextBin *gst.Bin, nextSong *SongInfo, nextVolumeElem *gst.Element, nextGain *float64) {
+		sinkPad := mixer.GetRequestPad("sink_%u")
+		nextBin.GetStaticPad("src").Link(sinkPad)
+		nextBin.SetState(gst.StatePlaying)
+
+		oldBin, oldVolumeElem := getActive()
+
+		p.mu.RLock()
+		target := p.volume
+		p.mu.RUnlock()
+
+		var oldBinding gst.ControlBinding
+		if oldVolumeElem != nil {
+			oldBinding = armCrossfadeRamp(pipeline, oldVolumeElem, target, 0, crossfadeMs)
+		}
+		nextBinding := armCrossfadeRamp(pipeline, nextVolumeElem, 0, target, crossfadeMs)
+
+		select {
+		case <-p.ctx.Done():
+			// Stop/Seek cancelled us mid-ramp. playbackLoop's own
+			// cancellation handling is about to tear the pipeline down
+			// on another goroutine, so don't touch oldBin, p.currentSong,
+			// or anything else still in play — just unwind what this
+			// goroutine itself linked in, mirroring the mid-preload-cancel
+			// cleanup in startGaplessPreload above.
+			if oldVolumeElem != nil {
+				oldVolumeElem.RemoveControlBinding(oldBinding)
+			}
+			nextVolumeElem.RemoveControlBinding(nextBinding)
+			nextSrcPad := nextBin.GetStaticPad("src")
+			nextSrcPad.Unlink(sinkPad)
+			nextBin.SetState(gst.StateNull)
+			pipeline.Remove(nextBin)
+			mixer.ReleaseRequestPad(sinkPad)
+			return
+		case <-time.After(time.Duration(crossfadeMs) * time.Millisecond):
+		}
+
+		if oldVolumeElem != nil {
+			oldVolumeElem.RemoveControlBinding(oldBinding)
+		}
+		nextVolumeElem.RemoveControlBinding(nextBinding)
+
+		oldSrcPad := oldBin.GetStaticPad("src")
+		oldSinkPad := oldSrcPad.GetPeer()
+		oldSrcPad.Unlink(oldSinkPad)
+		oldBin.SetState(gst.StateNull)
+		pipeline.Remove(oldBin)
+		if oldSinkPad != nil {
+			mixer.ReleaseRequestPad(oldSinkPad)
+		}
+
+		p.mu.Lock()
+		p.currentSong = nextSong
+		p.positionMs = 0
+		p.durationMs = int64(nextSong.Duration) * 1000
+		p.measuredGainDb = nextGain
+		p.volumeElem = nextVolumeElem
+		p.mu.Unlock()
+		p.popQueue()
+
+		song = nextSong
+		setActive(nextBin, nextVolumeElem)
+		preload = &gaplessPreload{}
+		armOverallTimeout(nextSong)
+		p.publishEvent("track_changed")
+
+		log.Printf("Crossfaded into next track: %s", nextSong.Name)
+	}
+
+	// reconnectAttempt tracks how many consecutive reconnectLive attempts
+	// have failed, so the backoff grows each time and resets once a
+	// reconnect actually starts playing again.
+	reconnectAttempt := 0
+
+	// reconnectLive tears down the dropped live source bin and rebuilds it
+	// after an exponentially growing delay, for as long as this playback
+	// session stays alive. Used for both EOS (server closed the connection)
+	// and a pipeline error on a live source. Rebuilding the source bin off
+	// the main loop is fine, but the actual pipeline edits are scheduled
+	// via glib.IdleAdd onto the same main-loop thread the bus watch runs
+	// on, instead of racing it from a bare goroutine. The reconnected bin
+	// is flushed in before it's linked: the sole live bin hitting EOS
+	// already forwarded that EOS through the mixer to opusenc/tee/appsink,
+	// and those won't accept buffers again without a flush.
+	var reconnectLive func()
+	reconnectLive = func() {
+		delay := liveReconnectBaseDelay << uint(reconnectAttempt)
+		if delay <= 0 || delay > liveReconnectMaxDelay {
+			delay = liveReconnectMaxDelay
+		}
+		reconnectAttempt++
+		log.Printf("Live stream %s dropped, reconnecting in %s (attempt %d)", song.Name, delay, reconnectAttempt)
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		newBin, newVolumeElem, _, _, err := buildSourceBin(p.ctx, song, normalizationMode, 0)
+		if err != nil {
+			log.Printf("Live reconnect failed to rebuild source for %s: %v", song.Name, err)
+			go reconnectLive()
+			return
+		}
+
+		glib.IdleAdd(func() bool {
+			oldBin, _ := getActive()
+			if oldSrcPad := oldBin.GetStaticPad("src"); oldSrcPad != nil {
+				if oldSinkPad := oldSrcPad.GetPeer(); oldSinkPad != nil {
+					oldSrcPad.Unlink(oldSinkPad)
+					mixer.ReleaseRequestPad(oldSinkPad)
+				}
+			}
+			oldBin.SetState(gst.StateNull)
+			pipeline.Remove(oldBin)
+
+			// Flush the shared encode tail before the new bin starts
+			// feeding it, to clear the EOS state opusenc/tee/appsink
+			// already latched when the old (sole) live bin EOS'd.
+			mixerSrcPad := mixer.GetStaticPad("src")
+			mixerSrcPad.SendEvent(gst.NewEventFlushStart())
+			mixerSrcPad.SendEvent(gst.NewEventFlushStop(true))
+
+			pipeline.Add(newBin)
+			newBin.GetStaticPad("src").Link(mixer.GetRequestPad("sink_%u"))
+			newBin.SetState(gst.StatePlaying)
+			setActive(newBin, newVolumeElem)
+
+			p.mu.Lock()
+			p.volumeElem = newVolumeElem
+			p.mu.Unlock()
+
+			p.mu.RLock()
+			target := p.volume
+			p.mu.RUnlock()
+			go animateVolume(p.ctx, newVolumeElem, 0, target, DefaultFadeMs)
+
+			reconnectAttempt = 0
+			log.Printf("Reconnected live stream: %s", song.Name)
+			return false
+		})
+	}
+
+	// Handle samples from appsink - exactly like Ingress output.go handleSample
+	appsink.SetCallbacks(&app.SinkCallbacks{
+		EOSFunc: func(sink *app.Sink) {
+			log.Printf("EOS received")
+		},
+		NewSampleFunc: func(sink *app.Sink) gst.FlowReturn {
+			sample := sink.PullSample()
+			if sample == nil {
+				return gst.FlowEOS
+			}
+
+			buffer := sample.GetBuffer()
+			if buffer == nil {
+				return gst.FlowError
+			}
+
+			duration := time.Duration(buffer.Duration())
+
+			// WriteSample - exactly like Ingress
+			err := track.WriteSample(media.Sample{
+				Data:     buffer.Bytes(),
+				Duration: duration,
+			}, nil)
+			if err != nil {
+				log.Printf("WriteSample error: %v", err)
+			}
+
+			// Update position
+			p.mu.Lock()
+			p.positionMs += int64(duration / time.Millisecond)
+			if p.positionMs > p.durationMs {
+				p.positionMs = p.durationMs
+			}
+			posMs, durMs := p.positionMs, p.durationMs
+			p.mu.Unlock()
+
+			// Kick off gapless preload once we're close to the end of the
+			// current track and there's something queued up next.
+			if durMs > 0 && durMs-posMs <= GaplessPreloadMs {
+				if next := p.peekNextInQueue(); next != nil && preload.markStarted() {
+					go startGaplessPreload(next)
+				}
+			}
+
+			// Once inside the crossfade window, bring the preloaded bin in
+			// early (if it's ready) instead of waiting for a hard EOS cut.
+			if durMs > 0 && durMs-posMs <= int64(crossfadeMs) {
+				if nextBin, nextSong, nextVolumeElem, nextGain, ready := preload.take(); ready && nextBin != nil && preload.markCrossfading() {
+					go startCrossfade(nextBin, nextSong, nextVolumeElem, nextGain)
+				}
+			}
+
+			return gst.FlowOK
+		},
+	})
+
+	// Seek if needed
+	if startPosMs > 0 {
+		pipeline.SetState(gst.StatePaused)
+		pipeline.Bin.Element.GetState(gst.StateNull, gst.ClockTimeNone)
+
+		// A raw MP3/AAC source has no seek index of its own, so a
+		// time-format seek degrades to uridecodebin scanning from byte 0;
+		// seek in bytes instead, using the bitrate-mapped offset
+		// sourceresolver computed when it sniffed this source.
+		byteOffset, useByteSeek := int64(0), false
+		if activeResolved != nil {
+			byteOffset, useByteSeek = activeResolved.SeekByteOffset(time.Duration(startPosMs) * time.Millisecond)
+		}
+		if useByteSeek {
+			pipeline.Bin.Element.SeekSimple(byteOffset, gst.FormatBytes, gst.SeekFlagFlush|gst.SeekFlagKeyUnit)
+		} else {
+			pipeline.Bin.Element.SeekSimple(int64(startPosMs)*int64(time.Millisecond), gst.FormatTime, gst.SeekFlagFlush|gst.SeekFlagKeyUnit)
+		}
+	}
+
+	// Start pipeline with loading timeout
+	loadingTimeout := time.AfterFunc(30*time.Second, func() {
+		log.Printf("Loading timeout for %s", song.Name)
+		p.mu.Lock()
+		if p.state == StateLoading || p.state == StatePlaying {
+			if p.cancel != nil {
+				p.cancel()
+			}
+			if p.loop != nil {
+				p.loop.Quit()
+			}
+			p.state = StateStopped
+		}
+		p.mu.Unlock()
+	})
+
+	pipeline.SetState(gst.StatePlaying)
+	go animateVolume(p.ctx, activeVolumeElem, 0, targetVolume, DefaultFadeMs)
+	p.publishEvent("started")
+
+	// Publish position/metadata on the data channel at ~4Hz so clients can
+	// track playback without polling /progress.
+	go func() {
+		ticker := time.NewTicker(progressPublishInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+				p.publishProgress()
+			}
+		}
+	}()
+
+	// Main loop
+	loop := glib.NewMainLoop(glib.MainContextDefault(), false)
 	p.mu.Lock()
 	p.loop = loop
 	p.mu.Unlock()
@@ -477,7 +1507,64 @@ func (p *Player) playbackLoop(song *SongInfo, startPosMs int64) {
 			if newState == gst.StatePlaying {
 				loadingTimeout.Stop()
 			}
+		case gst.MessageTag:
+			if song.kind() == SongKindLive {
+				if raw, ok := msg.ParseTag().GetString("title"); ok && raw != "" {
+					go notifyStreamMetadata(p.roomName, raw)
+				}
+			}
 		case gst.MessageEOS:
+			if song.kind() == SongKindLive {
+				log.Printf("Live stream ended/dropped: %s", song.Name)
+				go reconnectLive()
+				return true
+			}
+			if nextBin, nextSong, nextVolumeElem, nextGain, ready := preload.take(); ready && nextBin != nil {
+				// Gapless swap: splice the prerolled bin in place of the
+				// one that just hit EOS, without touching the shared
+				// opusenc/appsink tail or the published LiveKit track.
+				log.Printf("Gapless swap: %s -> %s", song.Name, nextSong.Name)
+
+				nextSrcPad := nextBin.GetStaticPad("src")
+				if !nextSrcPad.IsLinked() {
+					// Crossfade window never arrived (preload finished very
+					// late) - cut straight over instead, with a quick fade-in
+					// so it's not an audible jump from silence.
+					nextSrcPad.Link(mixer.GetRequestPad("sink_%u"))
+					nextBin.SetState(gst.StatePlaying)
+					p.mu.RLock()
+					target := p.volume
+					p.mu.RUnlock()
+					go animateVolume(p.ctx, nextVolumeElem, 0, target, DefaultFadeMs)
+				}
+
+				oldBin, _ := getActive()
+				oldSrcPad := oldBin.GetStaticPad("src")
+				oldSinkPad := oldSrcPad.GetPeer()
+				oldSrcPad.Unlink(oldSinkPad)
+				oldBin.SetState(gst.StateNull)
+				pipeline.Remove(oldBin)
+				if oldSinkPad != nil {
+					mixer.ReleaseRequestPad(oldSinkPad)
+				}
+
+				p.mu.Lock()
+				p.currentSong = nextSong
+				p.positionMs = 0
+				p.durationMs = int64(nextSong.Duration) * 1000
+				p.measuredGainDb = nextGain
+				p.volumeElem = nextVolumeElem
+				p.mu.Unlock()
+				p.popQueue()
+
+				song = nextSong
+				setActive(nextBin, nextVolumeElem)
+				preload = &gaplessPreload{}
+				armOverallTimeout(nextSong)
+				p.publishEvent("track_changed")
+				return true
+			}
+
 			log.Printf("Playback finished: %s", song.Name)
 			p.mu.Lock()
 			wasPlaying := p.state == StatePlaying
@@ -491,11 +1578,16 @@ func (p *Player) playbackLoop(song *SongInfo, startPosMs int64) {
 			if wasPlaying {
 				go notifySongEnded(roomName)
 			}
+			p.publishEvent("ended")
 			loop.Quit()
 			return false
 		case gst.MessageError:
 			err := msg.ParseError()
 			log.Printf("Pipeline error: %v", err)
+			if song.kind() == SongKindLive {
+				go reconnectLive()
+				return true
+			}
 			p.mu.Lock()
 			p.state = StateStopped
 			p.mu.Unlock()
@@ -514,16 +1606,38 @@ func (p *Player) playbackLoop(song *SongInfo, startPosMs int64) {
 
 	// Cleanup
 	loadingTimeout.Stop()
+	if preloadBin, _, _, _, ready := preload.take(); ready && preloadBin != nil {
+		// Stop/Seek landed mid-preload; tear the prerolled bin down too.
+		preloadBin.SetState(gst.StateNull)
+		pipeline.Remove(preloadBin)
+	}
 	p.cleanupPlayback(pipeline, room, pubSID)
 }
 
 const PauseTimeoutSeconds = 30
 
+// fadeOutCurrent ramps the active bin's volume down to 0 over fadeMs before
+// Pause/Stop/Seek tear the pipeline down, so the cut doesn't click. It's a
+// no-op if nothing is currently playing.
+func (p *Player) fadeOutCurrent(fadeMs int) {
+	p.mu.RLock()
+	playing := p.state == StatePlaying
+	volumeElem := p.volumeElem
+	from := p.volume
+	p.mu.RUnlock()
+
+	if playing && volumeElem != nil {
+		animateVolume(context.Background(), volumeElem, from, 0, fadeMs)
+	}
+}
+
 func (p *Player) Pause() {
+	p.fadeOutCurrent(DefaultFadeMs)
+
 	p.mu.Lock()
-	defer p.mu.Unlock()
 
 	if p.state != StatePlaying {
+		p.mu.Unlock()
 		return
 	}
 
@@ -554,6 +1668,9 @@ func (p *Player) Pause() {
 			log.Printf("Disconnected from room %s due to pause timeout", p.roomName)
 		}
 	})
+	p.mu.Unlock()
+
+	p.publishEvent("paused")
 }
 
 func (p *Player) cancelPauseTimer() {
@@ -563,111 +1680,408 @@ func (p *Player) cancelPauseTimer() {
 	}
 }
 
-func (p *Player) Resume() error {
+func (p *Player) Resume() error {
+	p.mu.Lock()
+
+	// Cancel pause timeout timer
+	p.cancelPauseTimer()
+
+	if p.state != StatePaused {
+		log.Printf("Resume: not paused, state=%s", p.state)
+		p.mu.Unlock()
+		return fmt.Errorf("not paused, state=%s", p.state)
+	}
+
+	song := p.currentSong
+	if song == nil {
+		log.Printf("Resume: no song loaded")
+		p.mu.Unlock()
+		return fmt.Errorf("no song loaded")
+	}
+
+	if p.cancel != nil {
+		p.cancel()
+	}
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.state = StatePlaying
+	startPos := p.positionMs
+	p.mu.Unlock()
+
+	// Ensure room connection before resuming (may need to reconnect after timeout)
+	if err := p.Connect(); err != nil {
+		log.Printf("Resume: failed to connect: %v", err)
+		p.mu.Lock()
+		p.state = StatePaused
+		p.mu.Unlock()
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+
+	log.Printf("Resuming from %dms", startPos)
+	p.publishEvent("resumed")
+	go p.playbackLoop(song, startPos)
+	return nil
+}
+
+func (p *Player) Seek(positionMs int64) error {
+	p.mu.RLock()
+	isLive := p.currentSong != nil && p.currentSong.kind() == SongKindLive
+	p.mu.RUnlock()
+	if isLive {
+		return fmt.Errorf("cannot seek a live stream")
+	}
+
+	p.fadeOutCurrent(DefaultFadeMs)
+
+	p.mu.Lock()
+	wasPlaying := p.state == StatePlaying
+	p.positionMs = positionMs
+
+	if p.cancel != nil {
+		p.cancel()
+	}
+
+	song := p.currentSong
+	p.mu.Unlock()
+
+	if wasPlaying && song != nil {
+		p.mu.Lock()
+		p.ctx, p.cancel = context.WithCancel(context.Background())
+		p.state = StatePlaying
+		p.mu.Unlock()
+		go p.playbackLoop(song, positionMs)
+	}
+	return nil
+}
+
+func (p *Player) Stop() {
+	p.fadeOutCurrent(DefaultFadeMs)
+
+	p.mu.Lock()
+
+	// Cancel pause timeout timer
+	p.cancelPauseTimer()
+
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+
+	if p.loop != nil {
+		p.loop.Quit()
+		p.loop = nil
+	}
+
+	if p.pipeline != nil {
+		p.pipeline.SetState(gst.StateNull)
+		p.pipeline = nil
+	}
+
+	p.state = StateStopped
+	room := p.room
+	roomName := p.roomName
+	song := p.currentSong
+	p.room = nil
+	p.track = nil
+	p.mu.Unlock()
+
+	if room != nil {
+		payload, err := json.Marshal(dataEventMessage{
+			Type:     "event",
+			Event:    "stopped",
+			RoomName: roomName,
+			Song:     song,
+		})
+		if err != nil {
+			log.Printf("Failed to marshal music-events message: %v", err)
+		} else if err := room.LocalParticipant.PublishData(payload, lksdk.WithDataPublishTopic(DataTopicEvents)); err != nil {
+			log.Printf("Failed to publish music-events message: %v", err)
+		}
+		room.Disconnect()
+	}
+}
+
+func (p *Player) GetProgress() (positionMs int64, durationMs int64, state PlayState, song *SongInfo, measuredGainDb *float64, broadcast *BroadcastConfig) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.positionMs, p.durationMs, p.state, p.currentSong, p.measuredGainDb, p.broadcastState
+}
+
+// StartBroadcast begins simulcasting the room's audio to an external
+// RTMP/Icecast target via a tee off the shared opusenc output. Fails if
+// nothing is currently playing in the room, or a broadcast is already
+// running.
+func (p *Player) StartBroadcast(cfg BroadcastConfig) error {
+	p.mu.Lock()
+	start := p.broadcastStart
+	p.mu.Unlock()
+	if start == nil {
+		return fmt.Errorf("nothing playing in this room")
+	}
+
+	if err := start(cfg); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.broadcastState = &cfg
+	p.mu.Unlock()
+	return nil
+}
+
+// StopBroadcast ends the room's active simulcast branch, if any.
+func (p *Player) StopBroadcast() {
+	p.mu.Lock()
+	stop := p.broadcastStop
+	p.broadcastState = nil
+	p.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// SetQueue replaces the pending queue outright, e.g. when /play is called
+// with an initial playlist.
+func (p *Player) SetQueue(songs []*SongInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queue = append([]*SongInfo{}, songs...)
+}
+
+// EnqueueSong appends a song to the end of the queue.
+func (p *Player) EnqueueSong(song *SongInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queue = append(p.queue, song)
+}
+
+// RemoveFromQueue removes the song at index from the queue.
+func (p *Player) RemoveFromQueue(index int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if index < 0 || index >= len(p.queue) {
+		return fmt.Errorf("queue index out of range: %d", index)
+	}
+	p.queue = append(p.queue[:index], p.queue[index+1:]...)
+	return nil
+}
+
+// ReorderQueue moves the song at index `from` to index `to`.
+func (p *Player) ReorderQueue(from, to int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if from < 0 || from >= len(p.queue) || to < 0 || to >= len(p.queue) {
+		return fmt.Errorf("queue index out of range")
+	}
+	song := p.queue[from]
+	rest := append(p.queue[:from], p.queue[from+1:]...)
+	moved := make([]*SongInfo, 0, len(p.queue))
+	moved = append(moved, rest[:to]...)
+	moved = append(moved, song)
+	moved = append(moved, rest[to:]...)
+	p.queue = moved
+	return nil
+}
+
+// ListQueue returns a snapshot of the pending queue.
+func (p *Player) ListQueue() []*SongInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]*SongInfo, len(p.queue))
+	copy(out, p.queue)
+	return out
+}
+
+// ClearQueue drops everything pending after the current song.
+func (p *Player) ClearQueue() {
 	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queue = nil
+}
 
-	// Cancel pause timeout timer
-	p.cancelPauseTimer()
-
-	if p.state != StatePaused {
-		log.Printf("Resume: not paused, state=%s", p.state)
-		p.mu.Unlock()
-		return fmt.Errorf("not paused, state=%s", p.state)
-	}
+// SetVolume updates the target volume for the room, animating the active
+// bin's `volume` element over fadeMs (jumping immediately if fadeMs <= 0).
+// The new target also becomes the fade-in level for the next track.
+func (p *Player) SetVolume(volume float64, fadeMs int) {
+	p.mu.Lock()
+	from := p.volume
+	p.volume = volume
+	volumeElem := p.volumeElem
+	ctx := p.ctx
+	p.mu.Unlock()
 
-	song := p.currentSong
-	if song == nil {
-		log.Printf("Resume: no song loaded")
-		p.mu.Unlock()
-		return fmt.Errorf("no song loaded")
+	if volumeElem == nil {
+		return
 	}
-
-	if p.cancel != nil {
-		p.cancel()
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	go animateVolume(ctx, volumeElem, from, volume, fadeMs)
+}
 
-	p.ctx, p.cancel = context.WithCancel(context.Background())
-	p.state = StatePlaying
-	startPos := p.positionMs
-	p.mu.Unlock()
+// dataProgressMessage is published on DataTopicProgress at
+// progressPublishInterval so room clients can drive a synced progress bar
+// without polling /progress.
+type dataProgressMessage struct {
+	Type           string    `json:"type"`
+	RoomName       string    `json:"room_name"`
+	PositionMs     int64     `json:"position_ms"`
+	DurationMs     int64     `json:"duration_ms"`
+	State          PlayState `json:"state"`
+	Song           *SongInfo `json:"song"`
+	MeasuredGainDb *float64  `json:"measured_gain_db,omitempty"`
+}
 
-	// Ensure room connection before resuming (may need to reconnect after timeout)
-	if err := p.Connect(); err != nil {
-		log.Printf("Resume: failed to connect: %v", err)
-		p.mu.Lock()
-		p.state = StatePaused
-		p.mu.Unlock()
-		return fmt.Errorf("failed to connect: %w", err)
-	}
+// dataEventMessage is published on DataTopicEvents once per state
+// transition (e.g. a track starting, ending, or playback being paused).
+type dataEventMessage struct {
+	Type     string    `json:"type"`
+	Event    string    `json:"event"`
+	RoomName string    `json:"room_name"`
+	Song     *SongInfo `json:"song,omitempty"`
+}
 
-	log.Printf("Resuming from %dms", startPos)
-	go p.playbackLoop(song, startPos)
-	return nil
+// ControlMessage is the inbound payload expected on DataTopicControl.
+// PositionMs is only used by the "seek" action.
+type ControlMessage struct {
+	Action     string `json:"action"`
+	PositionMs int64  `json:"position_ms,omitempty"`
 }
 
-func (p *Player) Seek(positionMs int64) {
-	p.mu.Lock()
-	wasPlaying := p.state == StatePlaying
-	p.positionMs = positionMs
+// publishProgress sends the current playback position/metadata on
+// DataTopicProgress. It's a no-op if the room isn't connected.
+func (p *Player) publishProgress() {
+	p.mu.RLock()
+	room := p.room
+	roomName := p.roomName
+	p.mu.RUnlock()
+	if room == nil {
+		return
+	}
 
-	if p.cancel != nil {
-		p.cancel()
+	posMs, durMs, state, song, gain, _ := p.GetProgress()
+	payload, err := json.Marshal(dataProgressMessage{
+		Type:           "progress",
+		RoomName:       roomName,
+		PositionMs:     posMs,
+		DurationMs:     durMs,
+		State:          state,
+		Song:           song,
+		MeasuredGainDb: gain,
+	})
+	if err != nil {
+		log.Printf("Failed to marshal music-progress message: %v", err)
+		return
+	}
+	if err := room.LocalParticipant.PublishData(payload, lksdk.WithDataPublishTopic(DataTopicProgress)); err != nil {
+		log.Printf("Failed to publish music-progress message: %v", err)
 	}
+}
 
+// publishEvent sends a one-shot state-transition notice on DataTopicEvents,
+// e.g. when a track starts, ends, or playback is paused/resumed/stopped.
+func (p *Player) publishEvent(event string) {
+	p.mu.RLock()
+	room := p.room
+	roomName := p.roomName
 	song := p.currentSong
-	p.mu.Unlock()
+	p.mu.RUnlock()
+	if room == nil {
+		return
+	}
 
-	if wasPlaying && song != nil {
-		p.mu.Lock()
-		p.ctx, p.cancel = context.WithCancel(context.Background())
-		p.state = StatePlaying
-		p.mu.Unlock()
-		go p.playbackLoop(song, positionMs)
+	payload, err := json.Marshal(dataEventMessage{
+		Type:     "event",
+		Event:    event,
+		RoomName: roomName,
+		Song:     song,
+	})
+	if err != nil {
+		log.Printf("Failed to marshal music-events message: %v", err)
+		return
+	}
+	if err := room.LocalParticipant.PublishData(payload, lksdk.WithDataPublishTopic(DataTopicEvents)); err != nil {
+		log.Printf("Failed to publish music-events message: %v", err)
 	}
 }
 
-func (p *Player) Stop() {
-	p.mu.Lock()
-
-	// Cancel pause timeout timer
-	p.cancelPauseTimer()
-
-	if p.cancel != nil {
-		p.cancel()
-		p.cancel = nil
+// handleControlMessage applies an inbound DataTopicControl message from
+// identity, if identity is on the DJ allowlist. Messages from other
+// identities, and unparseable or unknown-action messages, are logged and
+// dropped.
+func (p *Player) handleControlMessage(data []byte, identity string) {
+	if !manager.isDJ(identity) {
+		log.Printf("Ignoring music-control message from non-DJ identity %q", identity)
+		return
 	}
 
-	if p.loop != nil {
-		p.loop.Quit()
-		p.loop = nil
+	var msg ControlMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("Failed to parse music-control message: %v", err)
+		return
 	}
 
-	if p.pipeline != nil {
-		p.pipeline.SetState(gst.StateNull)
-		p.pipeline = nil
+	switch msg.Action {
+	case "pause":
+		p.Pause()
+	case "resume":
+		if err := p.Resume(); err != nil {
+			log.Printf("music-control resume failed: %v", err)
+		}
+	case "seek":
+		if err := p.Seek(msg.PositionMs); err != nil {
+			log.Printf("music-control seek failed: %v", err)
+		}
+	case "skip":
+		if err := p.Skip(); err != nil {
+			log.Printf("music-control skip failed: %v", err)
+		}
+	default:
+		log.Printf("Unknown music-control action: %q", msg.Action)
 	}
+}
 
-	p.state = StateStopped
-	room := p.room
-	p.room = nil
-	p.track = nil
-	p.mu.Unlock()
-
-	if room != nil {
-		room.Disconnect()
+// Skip stops the current track and plays the next queued song. If the queue
+// is empty it returns an error and leaves the current track playing.
+func (p *Player) Skip() error {
+	next := p.popQueue()
+	if next == nil {
+		return fmt.Errorf("queue is empty")
+	}
+	p.Stop()
+	if err := p.Load(next); err != nil {
+		return err
 	}
+	return p.Play()
 }
 
-func (p *Player) GetProgress() (positionMs int64, durationMs int64, state PlayState, song *SongInfo) {
+// peekNextInQueue returns the next queued song without removing it.
+func (p *Player) peekNextInQueue() *SongInfo {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	return p.positionMs, p.durationMs, p.state, p.currentSong
+	if len(p.queue) == 0 {
+		return nil
+	}
+	return p.queue[0]
+}
+
+// popQueue removes and returns the next queued song, if any.
+func (p *Player) popQueue() *SongInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == 0 {
+		return nil
+	}
+	song := p.queue[0]
+	p.queue = p.queue[1:]
+	return song
 }
 
 // HTTP Handlers
 type PlayRequest struct {
-	RoomName string    `json:"room_name"`
-	Song     *SongInfo `json:"song"`
+	RoomName string      `json:"room_name"`
+	Song     *SongInfo   `json:"song"`
+	Queue    []*SongInfo `json:"queue,omitempty"`
 }
 
 type RoomRequest struct {
@@ -675,6 +2089,12 @@ type RoomRequest struct {
 	PositionMs int64  `json:"position_ms,omitempty"`
 }
 
+type VolumeRequest struct {
+	RoomName string  `json:"room_name"`
+	Volume   float64 `json:"volume"`
+	FadeMs   int     `json:"fade_ms,omitempty"`
+}
+
 func handlePlay(c *gin.Context) {
 	var req PlayRequest
 	if err := c.BindJSON(&req); err != nil {
@@ -693,6 +2113,10 @@ func handlePlay(c *gin.Context) {
 		return
 	}
 
+	if req.Queue != nil {
+		player.SetQueue(req.Queue)
+	}
+
 	if err := player.Play(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -751,7 +2175,73 @@ func handleSeek(c *gin.Context) {
 		return
 	}
 
-	player.Seek(req.PositionMs)
+	if err := player.Seek(req.PositionMs); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func handleVolume(c *gin.Context) {
+	var req VolumeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	player, err := manager.GetOrCreatePlayer(req.RoomName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	player.SetVolume(req.Volume, req.FadeMs)
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+type BroadcastRequest struct {
+	RoomName   string        `json:"room_name"`
+	Mode       BroadcastMode `json:"mode"`
+	URL        string        `json:"url"`
+	Mount      string        `json:"mount,omitempty"`
+	StreamName string        `json:"stream_name,omitempty"`
+}
+
+func handleBroadcastStart(c *gin.Context) {
+	var req BroadcastRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	player, err := manager.GetOrCreatePlayer(req.RoomName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg := BroadcastConfig{Mode: req.Mode, URL: req.URL, Mount: req.Mount, StreamName: req.StreamName}
+	if err := player.StartBroadcast(cfg); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func handleBroadcastStop(c *gin.Context) {
+	var req RoomRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	player, err := manager.GetOrCreatePlayer(req.RoomName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	player.StopBroadcast()
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
@@ -785,12 +2275,14 @@ func handleProgress(c *gin.Context) {
 		return
 	}
 
-	pos, dur, state, song := player.GetProgress()
+	pos, dur, state, song, measuredGainDb, broadcast := player.GetProgress()
 	c.JSON(http.StatusOK, gin.H{
-		"position_ms": pos,
-		"duration_ms": dur,
-		"state":       state,
-		"song":        song,
+		"position_ms":      pos,
+		"duration_ms":      dur,
+		"state":            state,
+		"song":             song,
+		"measured_gain_db": measuredGainDb,
+		"broadcast":        broadcast,
 	})
 }
 
@@ -798,15 +2290,122 @@ func handleHealth(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+type QueueAddRequest struct {
+	RoomName string    `json:"room_name"`
+	Song     *SongInfo `json:"song"`
+}
+
+type QueueRemoveRequest struct {
+	RoomName string `json:"room_name"`
+	Index    int    `json:"index"`
+}
+
+type QueueReorderRequest struct {
+	RoomName string `json:"room_name"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+}
+
+func handleQueueAdd(c *gin.Context) {
+	var req QueueAddRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	player, err := manager.GetOrCreatePlayer(req.RoomName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	player.EnqueueSong(req.Song)
+	c.JSON(http.StatusOK, gin.H{"success": true, "queue": player.ListQueue()})
+}
+
+func handleQueueRemove(c *gin.Context) {
+	var req QueueRemoveRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	player, err := manager.GetOrCreatePlayer(req.RoomName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := player.RemoveFromQueue(req.Index); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "queue": player.ListQueue()})
+}
+
+func handleQueueReorder(c *gin.Context) {
+	var req QueueReorderRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	player, err := manager.GetOrCreatePlayer(req.RoomName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := player.ReorderQueue(req.From, req.To); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "queue": player.ListQueue()})
+}
+
+func handleQueueList(c *gin.Context) {
+	roomName := c.Query("room_name")
+	if roomName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "room_name required"})
+		return
+	}
+
+	player, err := manager.GetOrCreatePlayer(roomName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"queue": player.ListQueue()})
+}
+
+func handleQueueClear(c *gin.Context) {
+	var req RoomRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	player, err := manager.GetOrCreatePlayer(req.RoomName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	player.ClearQueue()
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
 func main() {
 	// Initialize GStreamer
 	gst.Init(nil)
 
 	config := &Config{
-		LiveKitURL:    os.Getenv("LIVEKIT_URL"),
-		LiveKitAPIKey: os.Getenv("LIVEKIT_API_KEY"),
-		LiveKitSecret: os.Getenv("LIVEKIT_API_SECRET"),
-		CallbackURL:   os.Getenv("CALLBACK_URL"),
+		LiveKitURL:        os.Getenv("LIVEKIT_URL"),
+		LiveKitAPIKey:     os.Getenv("LIVEKIT_API_KEY"),
+		LiveKitSecret:     os.Getenv("LIVEKIT_API_SECRET"),
+		CallbackURL:       os.Getenv("CALLBACK_URL"),
+		NormalizationMode: NormalizationMode(os.Getenv("NORMALIZATION_MODE")),
 	}
 
 	if config.LiveKitURL == "" {
@@ -821,6 +2420,21 @@ func main() {
 	if config.CallbackURL == "" {
 		config.CallbackURL = "http://127.0.0.1:8000"
 	}
+	switch config.NormalizationMode {
+	case NormalizationOff, NormalizationTrack, NormalizationAlbum, NormalizationAutoMeasure:
+	default:
+		config.NormalizationMode = NormalizationOff
+	}
+	if ms, err := strconv.Atoi(os.Getenv("CROSSFADE_MS")); err == nil {
+		config.CrossfadeMs = ms
+	}
+	if raw := os.Getenv("DJ_IDENTITIES"); raw != "" {
+		for _, id := range strings.Split(raw, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				config.DJIdentities = append(config.DJIdentities, id)
+			}
+		}
+	}
 
 	manager = NewPlayerManager(config)
 
@@ -837,8 +2451,16 @@ func main() {
 	r.POST("/pause", handlePause)
 	r.POST("/resume", handleResume)
 	r.POST("/seek", handleSeek)
+	r.POST("/volume", handleVolume)
+	r.POST("/broadcast/start", handleBroadcastStart)
+	r.POST("/broadcast/stop", handleBroadcastStop)
 	r.POST("/stop", handleStop)
 	r.GET("/progress", handleProgress)
+	r.POST("/queue/add", handleQueueAdd)
+	r.POST("/queue/remove", handleQueueRemove)
+	r.POST("/queue/reorder", handleQueueReorder)
+	r.GET("/queue/list", handleQueueList)
+	r.POST("/queue/clear", handleQueueClear)
 
 	log.Printf("Music service starting on port %s", port)
 	if err := r.Run(":" + port); err != nil {
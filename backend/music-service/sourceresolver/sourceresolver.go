@@ -0,0 +1,534 @@
+// Package sourceresolver sniffs a song's source URL ahead of playback so the
+// pipeline builder can reject unsupported formats early, get back a URI it
+// can hand straight to uridecodebin, and learn enough about the source
+// (sample rate/channels, approximate bitrate, whether it carries its own
+// seek index) to seek it accurately. It supports http(s)://, file://, and
+// s3:// URLs. s3:// is signed with AWS SigV4 query-string auth when
+// AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY are set in the environment
+// (AWS_SESSION_TOKEN and AWS_REGION/AWS_DEFAULT_REGION are honored too);
+// without credentials it falls back to the bucket's public virtual-hosted
+// HTTPS endpoint, so only public buckets resolve.
+//
+// This package deliberately does not try to bypass opusenc for
+// already-opus-48k-stereo sources. Every source bin feeds a shared
+// audiomixer ahead of a single opusenc instance (see playbackLoop in
+// the parent package) so per-room volume, fade, and crossfade all operate
+// on one mixed, consistently-encoded tail; skipping the mixer for some
+// sources but not others would mean those sources can't be faded or
+// crossfaded, which is a bigger redesign than format sniffing and belongs
+// in its own request if it's still wanted.
+package sourceresolver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Format identifies the codec/container a source was sniffed as.
+type Format string
+
+const (
+	FormatOpus    Format = "opus"
+	FormatVorbis  Format = "vorbis"
+	FormatMP3     Format = "mp3"
+	FormatAAC     Format = "aac"
+	FormatFLAC    Format = "flac"
+	FormatUnknown Format = "unknown"
+)
+
+// ErrUnsupportedFormat is returned by Resolve when the source's format was
+// positively identified and isn't one the pipeline can decode.
+var ErrUnsupportedFormat = errors.New("sourceresolver: unsupported source format")
+
+// probeBytes is how much of the body Resolve reads when sniffing magic
+// bytes, either from a file:// source or via the ranged GET resolveHTTP
+// falls back to.
+const probeBytes = 512
+
+// vbrTolerance is how far a format's own header bitrate and the bitrate
+// implied by ContentLength/expectedDuration can diverge before Resolve
+// flags VBRSuspected.
+const vbrTolerance = 0.15
+
+// Resolved describes a source after sniffing.
+type Resolved struct {
+	// URI is what the caller should hand to uridecodebin/souphttpsrc - the
+	// input URL unchanged for http(s):// and file://, a SigV4-presigned
+	// HTTPS URL for an authenticated s3://, or the bucket's public HTTPS
+	// endpoint for an unauthenticated one.
+	URI string
+	// Format is the sniffed codec, or FormatUnknown if it couldn't be
+	// determined (Resolve only returns ErrUnsupportedFormat when a format
+	// was positively identified as one we can't decode; it doesn't fail
+	// just because sniffing was inconclusive).
+	Format Format
+	// ContentLength is the source size in bytes, or -1 if unknown (e.g. a
+	// live stream or a HEAD response without Content-Length).
+	ContentLength int64
+	// AcceptRanges reports whether the source can be scrubbed via HTTP
+	// range requests (always true for file://).
+	AcceptRanges bool
+	// SampleRate and Channels are read from the format's own header
+	// (OpusHead, FLAC STREAMINFO, the Vorbis identification packet, or an
+	// MP3/ADTS frame header) when sniffing got far enough to find one, or
+	// 0 if it couldn't be determined.
+	SampleRate int
+	Channels   int
+	// BitrateKbps is the source's bitrate - read from an MP3/AAC frame
+	// header when one was sniffed, or estimated from
+	// ContentLength/expectedDuration otherwise. 0 if neither was
+	// available. This is what SeekByteOffset maps a time position through.
+	BitrateKbps int
+	// VBRSuspected is true when a format's own header bitrate and the
+	// bitrate implied by ContentLength/expectedDuration disagree by more
+	// than vbrTolerance, meaning the source is likely VBR-encoded and
+	// SeekByteOffset's bitrate-mapped offsets will only be approximate.
+	VBRSuspected bool
+}
+
+// NeedsByteRangeSeek reports whether the caller should seek this source by
+// computing a byte offset (via SeekByteOffset) rather than handing
+// GStreamer a plain time-format seek. MP3 and raw ADTS AAC streams have no
+// structured seek index of their own when served as a flat elementary
+// stream - unlike FLAC (which carries a seek table) or Ogg-contained
+// Opus/Vorbis (which the demuxer can seek by granule position) - so
+// uridecodebin's own seeking degrades to scanning from the start.
+func (r *Resolved) NeedsByteRangeSeek() bool {
+	return r.AcceptRanges && (r.Format == FormatMP3 || r.Format == FormatAAC)
+}
+
+// SeekByteOffset maps pos to an approximate byte offset using BitrateKbps,
+// for sources where NeedsByteRangeSeek is true. ok is false if this source
+// doesn't need byte-range seeking or no bitrate could be determined.
+// VBRSuspected sources still return an offset - it's the best estimate
+// available - but callers should expect it to land a little off the exact
+// position.
+func (r *Resolved) SeekByteOffset(pos time.Duration) (offset int64, ok bool) {
+	if !r.NeedsByteRangeSeek() || r.BitrateKbps <= 0 {
+		return 0, false
+	}
+	return int64(pos.Seconds() * float64(r.BitrateKbps) * 1000 / 8), true
+}
+
+// Resolve sniffs url's format, seekability, and audio parameters.
+// expectedDuration is the track's nominal duration if known (pass 0 when
+// it isn't); it's only used for the ContentLength-based bitrate estimate
+// and VBR sanity check, never to reject a source.
+//
+// For http(s):// it issues a HEAD request, falling back to a short ranged
+// GET when HEAD is rejected or doesn't return enough information to sniff
+// from. For file:// it stats the local path and sniffs its header bytes
+// directly. For s3:// it signs the request with AWS SigV4 query-string
+// auth when credentials are in the environment, or maps to the bucket's
+// public virtual-hosted HTTPS endpoint otherwise, and resolves that.
+func Resolve(ctx context.Context, rawURL string, expectedDuration time.Duration) (*Resolved, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("sourceresolver: invalid URL %q: %w", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return resolveFile(u, expectedDuration)
+	case "s3":
+		httpURL, err := s3URL(u)
+		if err != nil {
+			return nil, err
+		}
+		return resolveHTTP(ctx, httpURL, expectedDuration)
+	case "http", "https":
+		return resolveHTTP(ctx, rawURL, expectedDuration)
+	default:
+		return nil, fmt.Errorf("sourceresolver: unsupported URL scheme %q", u.Scheme)
+	}
+}
+
+func resolveFile(u *url.URL, expectedDuration time.Duration) (*Resolved, error) {
+	path := u.Path
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("sourceresolver: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("sourceresolver: %w", err)
+	}
+	defer f.Close()
+
+	head := make([]byte, probeBytes)
+	n, _ := f.Read(head)
+
+	format := sniffFormat("", head[:n])
+	if format == FormatUnknown && isObviouslyUnsupportedExt(path) {
+		return nil, ErrUnsupportedFormat
+	}
+
+	resolved := &Resolved{
+		URI:           u.String(),
+		Format:        format,
+		ContentLength: info.Size(),
+		AcceptRanges:  true,
+	}
+	applyAudioParams(resolved, head[:n], expectedDuration)
+	return resolved, nil
+}
+
+func resolveHTTP(ctx context.Context, rawURL string, expectedDuration time.Duration) (*Resolved, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	contentType := ""
+	contentLength := int64(-1)
+	acceptRanges := false
+	var head []byte
+
+	headReq, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err == nil {
+		if resp, err := client.Do(headReq); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				contentType = resp.Header.Get("Content-Type")
+				contentLength = resp.ContentLength
+				acceptRanges = strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes")
+			}
+		}
+	}
+
+	// HEAD either failed or didn't give us a content type that identifies
+	// the format - fall back to a short ranged GET to read the header bytes
+	// directly.
+	if sniffFormat(contentType, nil) == FormatUnknown {
+		rangeReq, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("sourceresolver: %w", err)
+		}
+		rangeReq.Header.Set("Range", fmt.Sprintf("bytes=0-%d", probeBytes-1))
+
+		resp, err := client.Do(rangeReq)
+		if err != nil {
+			return nil, fmt.Errorf("sourceresolver: probe request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("sourceresolver: probe request returned status %d", resp.StatusCode)
+		}
+		acceptRanges = acceptRanges || resp.StatusCode == http.StatusPartialContent
+
+		buf := make([]byte, probeBytes)
+		n, _ := resp.Body.Read(buf)
+		head = buf[:n]
+		if contentType == "" {
+			contentType = resp.Header.Get("Content-Type")
+		}
+		if contentLength < 0 && resp.ContentLength > 0 {
+			contentLength = resp.ContentLength
+		}
+	}
+
+	format := sniffFormat(contentType, head)
+	if format == FormatUnknown && isObviouslyUnsupportedContentType(contentType) {
+		return nil, ErrUnsupportedFormat
+	}
+
+	resolved := &Resolved{
+		URI:           rawURL,
+		Format:        format,
+		ContentLength: contentLength,
+		AcceptRanges:  acceptRanges,
+	}
+	applyAudioParams(resolved, head, expectedDuration)
+	return resolved, nil
+}
+
+// applyAudioParams fills in SampleRate/Channels/BitrateKbps/VBRSuspected on
+// an already-sniffed Resolved.
+func applyAudioParams(r *Resolved, head []byte, expectedDuration time.Duration) {
+	headerBitrateKbps := 0
+	switch r.Format {
+	case FormatOpus:
+		r.SampleRate, r.Channels = parseOpusHead(head)
+	case FormatFLAC:
+		r.SampleRate, r.Channels = parseFLACStreamInfo(head)
+	case FormatVorbis:
+		r.SampleRate, r.Channels = parseVorbisIdentHeader(head)
+	case FormatMP3:
+		r.SampleRate, r.Channels, headerBitrateKbps = parseMP3FrameHeader(head)
+	case FormatAAC:
+		r.SampleRate, r.Channels = parseADTSHeader(head)
+	}
+
+	estimatedBitrateKbps := 0
+	if r.ContentLength > 0 && expectedDuration > 0 {
+		estimatedBitrateKbps = int(float64(r.ContentLength) * 8 / expectedDuration.Seconds() / 1000)
+	}
+
+	switch {
+	case headerBitrateKbps > 0 && estimatedBitrateKbps > 0:
+		r.BitrateKbps = headerBitrateKbps
+		delta := float64(estimatedBitrateKbps-headerBitrateKbps) / float64(headerBitrateKbps)
+		if delta < 0 {
+			delta = -delta
+		}
+		r.VBRSuspected = delta > vbrTolerance
+	case headerBitrateKbps > 0:
+		r.BitrateKbps = headerBitrateKbps
+	default:
+		r.BitrateKbps = estimatedBitrateKbps
+	}
+}
+
+// sniffFormat identifies a format from its Content-Type header and/or
+// leading bytes. It returns FormatUnknown rather than guessing when neither
+// is conclusive - callers decide whether that's fatal.
+func sniffFormat(contentType string, head []byte) Format {
+	switch {
+	case strings.Contains(contentType, "audio/opus"):
+		return FormatOpus
+	case strings.Contains(contentType, "audio/mpeg"), strings.Contains(contentType, "audio/mp3"):
+		return FormatMP3
+	case strings.Contains(contentType, "audio/aac"):
+		return FormatAAC
+	case strings.Contains(contentType, "audio/flac"):
+		return FormatFLAC
+	}
+
+	switch {
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return FormatFLAC
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return FormatMP3
+	case len(head) > 1 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		// MPEG frame sync (audio/mpeg) and ADTS AAC (audio/aac) share the
+		// 11-bit 0xFFE sync pattern; layer bits distinguish them.
+		if head[1]&0x06 == 0 {
+			return FormatAAC
+		}
+		return FormatMP3
+	case bytes.HasPrefix(head, []byte("OggS")):
+		if bytes.Contains(head, []byte("OpusHead")) {
+			return FormatOpus
+		}
+		if bytes.Contains(head, []byte("vorbis")) {
+			return FormatVorbis
+		}
+	}
+
+	return FormatUnknown
+}
+
+// parseOpusHead reads the channel count and input sample rate out of an Ogg
+// Opus identification header (RFC 7845 section 5.1). The header is at a
+// fixed offset within the Ogg page: 27 bytes of fixed page header + however
+// many segment-table bytes the page declares, then "OpusHead".
+func parseOpusHead(head []byte) (sampleRate, channels int) {
+	idx := bytes.Index(head, []byte("OpusHead"))
+	if idx < 0 || idx+19 > len(head) {
+		return 0, 0
+	}
+	body := head[idx:]
+	channels = int(body[9])
+	sampleRate = int(body[12]) | int(body[13])<<8 | int(body[14])<<16 | int(body[15])<<24
+	return sampleRate, channels
+}
+
+// parseVorbisIdentHeader reads the channel count and sample rate out of the
+// Vorbis identification header packet (type 1, preceded by "\x01vorbis").
+func parseVorbisIdentHeader(head []byte) (sampleRate, channels int) {
+	marker := []byte("\x01vorbis")
+	idx := bytes.Index(head, marker)
+	if idx < 0 || idx+len(marker)+9 > len(head) {
+		return 0, 0
+	}
+	body := head[idx+len(marker):]
+	// vorbis_version(4) then audio_channels(1), audio_sample_rate(4) LE.
+	channels = int(body[4])
+	sampleRate = int(body[5]) | int(body[6])<<8 | int(body[7])<<16 | int(body[8])<<24
+	return sampleRate, channels
+}
+
+// parseFLACStreamInfo reads the sample rate and channel count out of the
+// mandatory STREAMINFO metadata block that immediately follows the "fLaC"
+// magic: a 4-byte block header, then a 34-byte STREAMINFO payload whose
+// bytes 10-17 pack sample rate (20 bits), channels-minus-1 (3 bits),
+// bits-per-sample-minus-1 (5 bits), and total samples (36 bits).
+func parseFLACStreamInfo(head []byte) (sampleRate, channels int) {
+	if !bytes.HasPrefix(head, []byte("fLaC")) || len(head) < 4+4+18 {
+		return 0, 0
+	}
+	info := head[4+4:] // skip "fLaC" + metadata block header
+	sampleRate = int(info[10])<<12 | int(info[11])<<4 | int(info[12])>>4
+	channels = int((info[12]>>1)&0x07) + 1
+	return sampleRate, channels
+}
+
+// mp3BitrateTableV1L3 and mp3SampleRateTable cover MPEG-1 Layer III, the
+// overwhelming majority of MP3 sources; less common combinations (MPEG-2/
+// 2.5, Layer I/II) are left as 0/unknown rather than guessed at.
+var mp3BitrateTableV1L3 = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+var mp3SampleRateTable = [4]int{44100, 48000, 32000, 0}
+
+// parseMP3FrameHeader scans head for an MPEG-1 Layer III frame sync and
+// decodes its bitrate, sample rate, and channel mode. Returns all-zero if
+// no frame sync was found (e.g. the probe only captured an ID3v2 tag
+// longer than probeBytes).
+func parseMP3FrameHeader(head []byte) (sampleRate, channels, bitrateKbps int) {
+	for i := 0; i+3 < len(head); i++ {
+		if head[i] != 0xFF || head[i+1]&0xFE != 0xFA {
+			continue // sync + MPEG-1 + Layer III (ignoring the protection bit)
+		}
+		bitrateIdx := head[i+2] >> 4
+		sampleRateIdx := (head[i+2] >> 2) & 0x03
+		channelMode := head[i+3] >> 6
+
+		br := mp3BitrateTableV1L3[bitrateIdx]
+		sr := mp3SampleRateTable[sampleRateIdx]
+		if br == 0 || sr == 0 {
+			continue
+		}
+		channels = 2
+		if channelMode == 0x03 {
+			channels = 1
+		}
+		return sr, channels, br
+	}
+	return 0, 0, 0
+}
+
+var adtsSampleRateTable = [16]int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350, 0, 0, 0}
+
+// parseADTSHeader decodes the sampling frequency and channel configuration
+// out of a raw ADTS AAC frame header (7 or 9 bytes, syncword 0xFFF).
+func parseADTSHeader(head []byte) (sampleRate, channels int) {
+	for i := 0; i+3 < len(head); i++ {
+		if head[i] != 0xFF || head[i+1]&0xF6 != 0xF0 {
+			continue
+		}
+		sampleRateIdx := (head[i+2] >> 2) & 0x0F
+		channelCfg := ((head[i+2] & 0x01) << 2) | (head[i+3] >> 6)
+		sr := adtsSampleRateTable[sampleRateIdx]
+		if sr == 0 {
+			continue
+		}
+		return sr, int(channelCfg)
+	}
+	return 0, 0
+}
+
+var unsupportedExts = map[string]bool{
+	".wma":  true,
+	".ra":   true,
+	".mid":  true,
+	".midi": true,
+}
+
+func isObviouslyUnsupportedExt(path string) bool {
+	for ext := range unsupportedExts {
+		if strings.HasSuffix(strings.ToLower(path), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func isObviouslyUnsupportedContentType(contentType string) bool {
+	switch {
+	case strings.Contains(contentType, "audio/x-ms-wma"):
+		return true
+	case strings.Contains(contentType, "video/"):
+		return true
+	case strings.Contains(contentType, "text/html"):
+		return true
+	}
+	return false
+}
+
+// s3URL maps an s3://bucket/key URL to an HTTPS one: SigV4 query-string
+// presigned when AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY are set in the
+// environment, or the bucket's public virtual-hosted endpoint otherwise.
+func s3URL(u *url.URL) (string, error) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return fmt.Sprintf("https://%s.s3.amazonaws.com%s", u.Host, u.Path), nil
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return presignS3GET(u.Host, u.Path, accessKey, secretKey, os.Getenv("AWS_SESSION_TOKEN"), region)
+}
+
+// presignS3GET builds a SigV4 query-string presigned GET URL (valid for 1
+// hour) per AWS's "Authenticating Requests: Using Query Parameters"
+// algorithm, using only the stdlib - no AWS SDK dependency.
+func presignS3GET(bucket, key, accessKey, secretKey, sessionToken, region string) (string, error) {
+	host := fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, region)
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	credentialScope := fmt.Sprintf("%s/%s/s3/aws4_request", dateStamp, region)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", accessKey+"/"+credentialScope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", "3600")
+	query.Set("X-Amz-SignedHeaders", "host")
+	if sessionToken != "" {
+		query.Set("X-Amz-Security-Token", sessionToken)
+	}
+	canonicalQuery := query.Encode()
+
+	canonicalURI := key
+	if !strings.HasPrefix(canonicalURI, "/") {
+		canonicalURI = "/" + canonicalURI
+	}
+	canonicalHeaders := "host:" + host + "\n"
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		canonicalURI,
+		canonicalQuery,
+		canonicalHeaders,
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		credentialScope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256(hmacSHA256(hmacSHA256(hmacSHA256([]byte("AWS4"+secretKey), dateStamp), region), "s3"), "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return fmt.Sprintf("https://%s%s?%s&X-Amz-Signature=%s", host, canonicalURI, canonicalQuery, signature), nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
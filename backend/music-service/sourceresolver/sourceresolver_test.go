@@ -0,0 +1,145 @@
+package sourceresolver
+
+import "testing"
+
+func TestSniffFormat(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		head        []byte
+		want        Format
+	}{
+		{"content-type opus", "audio/opus", nil, FormatOpus},
+		{"content-type mpeg", "audio/mpeg", nil, FormatMP3},
+		{"content-type mp3 alias", "audio/mp3", nil, FormatMP3},
+		{"content-type aac", "audio/aac", nil, FormatAAC},
+		{"content-type flac", "audio/flac", nil, FormatFLAC},
+		{"flac magic", "", []byte("fLaC\x00\x00\x00\x22"), FormatFLAC},
+		{"id3 tag", "", []byte("ID3\x04\x00\x00\x00\x00\x00\x00"), FormatMP3},
+		{"mpeg frame sync", "", []byte{0xFF, 0xFB, 0x90, 0x00}, FormatMP3},
+		{"adts frame sync", "", []byte{0xFF, 0xF1, 0x4C, 0x80}, FormatAAC},
+		{"ogg opus", "", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00OpusHead\x01\x02"), FormatOpus},
+		{"ogg vorbis", "", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00\x01vorbis\x00\x00\x00\x00"), FormatVorbis},
+		{"ogg without a known codec id", "", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00"), FormatUnknown},
+		{"nothing conclusive", "application/octet-stream", []byte{0x00, 0x01, 0x02}, FormatUnknown},
+		{"empty", "", nil, FormatUnknown},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sniffFormat(tc.contentType, tc.head); got != tc.want {
+				t.Errorf("sniffFormat(%q, %v) = %v, want %v", tc.contentType, tc.head, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsObviouslyUnsupportedExt(t *testing.T) {
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"/music/song.wma", true},
+		{"/music/SONG.WMA", true},
+		{"/music/oldie.ra", true},
+		{"/music/ringtone.mid", true},
+		{"/music/ringtone.midi", true},
+		{"/music/song.mp3", false},
+		{"/music/song.flac", false},
+		{"/music/no-extension", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			if got := isObviouslyUnsupportedExt(tc.path); got != tc.want {
+				t.Errorf("isObviouslyUnsupportedExt(%q) = %v, want %v", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsObviouslyUnsupportedContentType(t *testing.T) {
+	cases := []struct {
+		contentType string
+		want        bool
+	}{
+		{"audio/x-ms-wma", true},
+		{"video/mp4", true},
+		{"text/html; charset=utf-8", true},
+		{"audio/mpeg", false},
+		{"audio/opus", false},
+		{"", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.contentType, func(t *testing.T) {
+			if got := isObviouslyUnsupportedContentType(tc.contentType); got != tc.want {
+				t.Errorf("isObviouslyUnsupportedContentType(%q) = %v, want %v", tc.contentType, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseOpusHead(t *testing.T) {
+	head := []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00OpusHead\x01\x02\x38\x01\x80\xbb\x00\x00\x00\x00\x00")
+	sampleRate, channels := parseOpusHead(head)
+	if sampleRate != 48000 || channels != 2 {
+		t.Errorf("parseOpusHead = (%d, %d), want (48000, 2)", sampleRate, channels)
+	}
+}
+
+func TestParseFLACStreamInfo(t *testing.T) {
+	// "fLaC" + 4-byte metadata block header + STREAMINFO, with sample_rate
+	// = 44100 (0x0AC44) and channels-1 = 1 (stereo) packed into the 20/3
+	// bit fields at STREAMINFO byte offset 10-12.
+	info := make([]byte, 18)
+	info[10] = 0x0A
+	info[11] = 0xC4
+	info[12] = 0x42 // low 4 bits of sample rate (0x4) | channels-1 (1) << 1
+	head := append([]byte("fLaC\x00\x00\x00\x22"), info...)
+
+	sampleRate, channels := parseFLACStreamInfo(head)
+	if sampleRate != 44100 || channels != 2 {
+		t.Errorf("parseFLACStreamInfo = (%d, %d), want (44100, 2)", sampleRate, channels)
+	}
+}
+
+func TestParseMP3FrameHeader(t *testing.T) {
+	// MPEG-1 Layer III, 128kbps, 44100Hz, stereo: 0xFF 0xFB 0x90 0x00.
+	head := []byte{0x00, 0xFF, 0xFB, 0x90, 0x00}
+	sampleRate, channels, bitrateKbps := parseMP3FrameHeader(head)
+	if sampleRate != 44100 || channels != 2 || bitrateKbps != 128 {
+		t.Errorf("parseMP3FrameHeader = (%d, %d, %d), want (44100, 2, 128)", sampleRate, channels, bitrateKbps)
+	}
+}
+
+func TestParseADTSHeader(t *testing.T) {
+	// 48000Hz (index 3), 2 channels.
+	head := []byte{0xFF, 0xF1, 0x4C, 0x80}
+	sampleRate, channels := parseADTSHeader(head)
+	if sampleRate != 48000 || channels != 2 {
+		t.Errorf("parseADTSHeader = (%d, %d), want (48000, 2)", sampleRate, channels)
+	}
+}
+
+func TestResolvedSeekByteOffset(t *testing.T) {
+	r := &Resolved{Format: FormatMP3, AcceptRanges: true, BitrateKbps: 128}
+	offset, ok := r.SeekByteOffset(10 * 1e9) // 10s, in time.Duration nanoseconds
+	if !ok {
+		t.Fatalf("SeekByteOffset: ok = false, want true")
+	}
+	want := int64(10 * 128 * 1000 / 8)
+	if offset != want {
+		t.Errorf("SeekByteOffset = %d, want %d", offset, want)
+	}
+
+	flac := &Resolved{Format: FormatFLAC, AcceptRanges: true, BitrateKbps: 128}
+	if _, ok := flac.SeekByteOffset(10 * 1e9); ok {
+		t.Errorf("SeekByteOffset on FLAC (has its own seek table): ok = true, want false")
+	}
+
+	noBitrate := &Resolved{Format: FormatMP3, AcceptRanges: true}
+	if _, ok := noBitrate.SeekByteOffset(10 * 1e9); ok {
+		t.Errorf("SeekByteOffset with no bitrate known: ok = true, want false")
+	}
+}